@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
+)
+
+// ------------------------------------------------------------
+// /api/v2/generate: typed request and response, RFC 7807 errors
+// ------------------------------------------------------------
+
+// v2Response is the body of a successful /api/v2/generate call.
+type v2Response struct {
+	Dictionary string       `json:"dictionary"`
+	Policy     string       `json:"policy"`
+	Passwords  []v2Password `json:"passwords"`
+}
+
+// v2Password is one generated password with its metadata.
+type v2Password struct {
+	Password string                `json:"password"`
+	Mode     string                `json:"mode"`
+	Fallback bool                  `json:"fallback"`
+	Entropy  float64               `json:"entropy"`
+	Length   int                   `json:"length"`
+	Classes  generator.ClassCounts `json:"classes"`
+	Dice     []string              `json:"dice,omitempty"`
+	Rejected int                   `json:"rejected,omitempty"`
+	Screened int                   `json:"screened,omitempty"`
+}
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Field names the offending request field, for invalid-parameter.
+	Field string `json:"field,omitempty"`
+}
+
+// Problem types returned by the v2 API.
+const (
+	problemInvalidBody      = "urn:password-o-matic:problem:invalid-body"
+	problemInvalidParameter = "urn:password-o-matic:problem:invalid-parameter"
+	problemUnknownMode      = "urn:password-o-matic:problem:unknown-mode"
+	problemUnsatisfiable    = "urn:password-o-matic:problem:unsatisfiable"
+	problemGenerationFailed = "urn:password-o-matic:problem:generation-failed"
+	problemMethodNotAllowed = "urn:password-o-matic:problem:method-not-allowed"
+	problemNoDictionary     = "urn:password-o-matic:problem:no-dictionary"
+)
+
+func writeProblem(w http.ResponseWriter, p problem) {
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// generateProblem turns an error from parsing or generation into a problem.
+func generateProblem(err error) problem {
+	var pe *paramError
+	if errors.As(err, &pe) {
+		return problem{Type: problemInvalidParameter, Title: "Invalid parameter", Status: http.StatusBadRequest, Detail: pe.Message, Field: pe.Field}
+	}
+	if errors.Is(err, generator.ErrNoWords) {
+		return problem{Type: problemNoDictionary, Title: "No dictionary loaded", Status: http.StatusServiceUnavailable, Detail: "this mode needs a dictionary and none could be loaded; see /api/status"}
+	}
+	if status := generateErrorStatus(err); status != http.StatusInternalServerError {
+		return problem{Type: problemUnsatisfiable, Title: "Cannot satisfy request", Status: status, Detail: err.Error()}
+	}
+	return problem{Type: problemGenerationFailed, Title: "Generation failed", Status: http.StatusInternalServerError, Detail: err.Error()}
+}
+
+func apiV2GenerateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeProblem(w, problem{Type: problemMethodNotAllowed, Status: http.StatusMethodNotAllowed, Detail: "use POST with a JSON body"})
+		return
+	}
+	// The body takes the same fields as the v1 query string. Unlike v1, an
+	// unknown mode is an error rather than a silent fallback to normal.
+	var req genParams
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeProblem(w, problem{Type: problemInvalidBody, Title: "Invalid request body", Status: http.StatusBadRequest, Detail: err.Error()})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = "normal"
+	}
+	if _, ok := generator.Lookup(req.Mode); !ok {
+		writeProblem(w, problem{Type: problemUnknownMode, Title: "Unknown mode", Status: http.StatusBadRequest, Detail: fmt.Sprintf("mode %q is not registered", req.Mode), Field: "mode"})
+		return
+	}
+	n, err := req.count()
+	if err != nil {
+		writeProblem(w, generateProblem(err))
+		return
+	}
+	if req.Dict == "" {
+		// name the default in the response, even if a reload changes it
+		req.Dict = dicts.Load().def
+	}
+	g, err := req.generator()
+	if err != nil {
+		writeProblem(w, generateProblem(err))
+		return
+	}
+
+	resp := v2Response{Dictionary: req.Dict, Policy: g.Policy.Name, Passwords: make([]v2Password, 0, n)}
+	for i := 0; i < n; i++ {
+		res, err := generate(r.Context(), g, req.Mode)
+		if err != nil {
+			writeProblem(w, generateProblem(err))
+			return
+		}
+		resp.Passwords = append(resp.Passwords, v2PasswordFrom(res))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		writeProblem(w, problem{Type: problemGenerationFailed, Status: http.StatusInternalServerError, Detail: "encode json: " + err.Error()})
+		return
+	}
+}
+
+func v2PasswordFrom(res generator.Result) v2Password {
+	return v2Password{
+		Password: res.Password,
+		Mode:     res.Mode,
+		Fallback: res.FellBack,
+		Entropy:  roundBits(res.Entropy),
+		Length:   utf8.RuneCountInString(res.Password),
+		Classes:  generator.CountClasses(res.Password),
+		Dice:     res.Dice,
+		Rejected: res.Rejected,
+		Screened: res.Screened,
+	}
+}
@@ -0,0 +1,170 @@
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// bloomMagic starts a Bloom filter file. It is followed by a version byte,
+// the hash type byte, k and m as big-endian uint32 and uint64, and m bits.
+var bloomMagic = []byte("PWOBLOOM")
+
+const bloomVersion = 1
+
+// Bloom is a Bloom filter over the hashes of a Pwned Passwords file. It
+// answers in memory with no disk access, at the cost of a small chance
+// of reporting a password that is not in the data. Build one with
+// BuildBloom.
+type Bloom struct {
+	typ  HashType
+	k    uint32
+	m    uint64
+	bits []byte
+}
+
+// Type returns the hash the filter is keyed by.
+func (b *Bloom) Type() HashType { return b.typ }
+
+// Lookup returns 1 if the password is probably in the breach data.
+func (b *Bloom) Lookup(ctx context.Context, password string) (int, error) {
+	if b.contains(b.typ.Sum(password)) {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// indexes derives the k bit positions from the digest by double hashing;
+// the digest is already uniformly distributed.
+func (b *Bloom) indexes(digest []byte, fn func(uint64)) {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16]) | 1
+	for i := uint64(0); i < uint64(b.k); i++ {
+		fn((h1 + i*h2) % b.m)
+	}
+}
+
+func (b *Bloom) contains(digest []byte) bool {
+	found := true
+	b.indexes(digest, func(i uint64) {
+		if b.bits[i/8]&(1<<(i%8)) == 0 {
+			found = false
+		}
+	})
+	return found
+}
+
+func (b *Bloom) add(digest []byte) {
+	b.indexes(digest, func(i uint64) { b.bits[i/8] |= 1 << (i % 8) })
+}
+
+// BuildBloom reads a Pwned Passwords hash file from r and returns a filter
+// holding its n hashes with false-positive rate fp. n sizes the filter and
+// may be an estimate, such as the file size divided by the line length.
+func BuildBloom(r io.Reader, n int, fp float64) (*Bloom, error) {
+	if n < 1 || fp <= 0 || fp >= 1 {
+		return nil, fmt.Errorf("need n >= 1 and 0 < fp < 1, got n=%d fp=%g", n, fp)
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fp) / (math.Ln2 * math.Ln2)))
+	m = (m + 7) &^ 7
+	k := uint32(max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	b := &Bloom{k: k, m: m, bits: make([]byte, m/8)}
+
+	sc := bufio.NewScanner(r)
+	first := true
+	for sc.Scan() {
+		hash, _, ok := splitLine(sc.Bytes())
+		if !ok {
+			return nil, fmt.Errorf("%w: bad line %q", ErrFormat, sc.Text())
+		}
+		if first {
+			switch len(hash) {
+			case SHA1.hexLen():
+				b.typ = SHA1
+			case NTLM.hexLen():
+				b.typ = NTLM
+			default:
+				return nil, fmt.Errorf("%w: %d-digit hash is neither SHA-1 nor NTLM", ErrFormat, len(hash))
+			}
+			first = false
+		}
+		if len(hash) != b.typ.hexLen() {
+			return nil, fmt.Errorf("%w: hash %q has the wrong length", ErrFormat, hash)
+		}
+		digest := make([]byte, hex.DecodedLen(len(hash)))
+		if _, err := hex.Decode(digest, hash); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+		}
+		b.add(digest)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if first {
+		return nil, fmt.Errorf("%w: no hashes", ErrFormat)
+	}
+	return b, nil
+}
+
+// WriteTo writes the filter in the format LoadBloom reads.
+func (b *Bloom) WriteTo(w io.Writer) (int64, error) {
+	var hdr bytes.Buffer
+	hdr.Write(bloomMagic)
+	hdr.WriteByte(bloomVersion)
+	hdr.WriteByte(byte(b.typ))
+	binary.Write(&hdr, binary.BigEndian, b.k)
+	binary.Write(&hdr, binary.BigEndian, b.m)
+	n, err := w.Write(hdr.Bytes())
+	if err != nil {
+		return int64(n), err
+	}
+	n2, err := w.Write(b.bits)
+	return int64(n + n2), err
+}
+
+// IsBloomFile reports whether the file at path starts like a Bloom filter
+// written by WriteTo.
+func IsBloomFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	head := make([]byte, len(bloomMagic))
+	if _, err := io.ReadFull(f, head); err != nil {
+		return false, nil
+	}
+	return bytes.Equal(head, bloomMagic), nil
+}
+
+// LoadBloom reads a filter written by WriteTo into memory.
+func LoadBloom(path string) (*Bloom, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	hdrLen := len(bloomMagic) + 2 + 4 + 8
+	if len(data) < hdrLen || !bytes.Equal(data[:len(bloomMagic)], bloomMagic) {
+		return nil, fmt.Errorf("%w: %s is not a Bloom filter", ErrFormat, path)
+	}
+	p := data[len(bloomMagic):]
+	if p[0] != bloomVersion {
+		return nil, fmt.Errorf("%w: %s has Bloom filter version %d, want %d", ErrFormat, path, p[0], bloomVersion)
+	}
+	b := &Bloom{
+		typ: HashType(p[1]),
+		k:   binary.BigEndian.Uint32(p[2:6]),
+		m:   binary.BigEndian.Uint64(p[6:14]),
+	}
+	b.bits = data[hdrLen:]
+	if b.typ != SHA1 && b.typ != NTLM || b.k == 0 || b.m == 0 || b.m%8 != 0 || uint64(len(b.bits)) != b.m/8 {
+		return nil, fmt.Errorf("%w: %s has a corrupt Bloom filter header", ErrFormat, path)
+	}
+	return b, nil
+}
@@ -0,0 +1,102 @@
+// Package breach screens passwords against known breach data in the
+// Pwned Passwords format: a sorted file of hashes searched on disk, a
+// Bloom filter built from such a file, or a k-anonymity range API.
+//
+// All three compare upper-case hex hashes of the password, either SHA-1
+// or NTLM (MD4 of the UTF-16LE password), so the password itself never
+// leaves the process.
+package breach
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// ErrFormat is returned for a hash file or Bloom filter that cannot be read.
+var ErrFormat = errors.New("unrecognised breach data format")
+
+// Checker looks passwords up in breach data.
+type Checker interface {
+	// Lookup returns how often password appears in the breach data, 0 if
+	// it does not. Sources that only know whether a password appears,
+	// like a Bloom filter, return 1.
+	Lookup(ctx context.Context, password string) (int, error)
+}
+
+// HashType is the hash a source is keyed by.
+type HashType int
+
+const (
+	SHA1 HashType = iota
+	NTLM
+)
+
+func (h HashType) String() string {
+	if h == NTLM {
+		return "ntlm"
+	}
+	return "sha1"
+}
+
+// hexLen is the length of the hash in hex digits.
+func (h HashType) hexLen() int {
+	if h == NTLM {
+		return 32
+	}
+	return 40
+}
+
+// ParseHashType accepts "sha1" or "ntlm".
+func ParseHashType(s string) (HashType, error) {
+	switch strings.ToLower(s) {
+	case "sha1", "sha-1":
+		return SHA1, nil
+	case "ntlm":
+		return NTLM, nil
+	}
+	return 0, fmt.Errorf("unknown hash type %q (want sha1 or ntlm)", s)
+}
+
+// Sum returns the raw hash of password.
+func (h HashType) Sum(password string) []byte {
+	if h == NTLM {
+		m := md4.New()
+		for _, u := range utf16.Encode([]rune(password)) {
+			m.Write([]byte{byte(u), byte(u >> 8)})
+		}
+		return m.Sum(nil)
+	}
+	sum := sha1.Sum([]byte(password))
+	return sum[:]
+}
+
+// Hex returns the hash of password as upper-case hex, as in the Pwned
+// Passwords files.
+func (h HashType) Hex(password string) string {
+	return strings.ToUpper(hex.EncodeToString(h.Sum(password)))
+}
+
+// Any returns a Checker that consults each of checkers in turn and
+// reports the first hit.
+func Any(checkers ...Checker) Checker {
+	return anyChecker(checkers)
+}
+
+type anyChecker []Checker
+
+func (a anyChecker) Lookup(ctx context.Context, password string) (int, error) {
+	for _, c := range a {
+		n, err := c.Lookup(ctx, password)
+		if err != nil || n > 0 {
+			return n, err
+		}
+	}
+	return 0, nil
+}
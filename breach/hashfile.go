@@ -0,0 +1,172 @@
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// HashFile is a Pwned Passwords download: one "HASH:COUNT" line per
+// password, sorted by hash. It is binary-searched on disk, so files of
+// tens of gigabytes need no memory.
+type HashFile struct {
+	f    *os.File
+	size int64
+	typ  HashType
+}
+
+// scanWindow is the size below which the search reads lines in order
+// instead of bisecting further.
+const scanWindow = 8 << 10
+
+// OpenHashFile opens a sorted hash file. The hash type is taken from the
+// length of the first hash.
+func OpenHashFile(path string) (*HashFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	h := &HashFile{f: f, size: st.Size()}
+	line, _, err := h.lineAt(0)
+	if err != nil && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+	hash, _, ok := splitLine(line)
+	switch {
+	case ok && len(hash) == SHA1.hexLen():
+		h.typ = SHA1
+	case ok && len(hash) == NTLM.hexLen():
+		h.typ = NTLM
+	default:
+		f.Close()
+		return nil, fmt.Errorf("%w: %s does not start with a SHA-1 or NTLM \"HASH:COUNT\" line", ErrFormat, path)
+	}
+	return h, nil
+}
+
+// Type returns the hash the file is keyed by.
+func (h *HashFile) Type() HashType { return h.typ }
+
+// Close closes the file.
+func (h *HashFile) Close() error { return h.f.Close() }
+
+// Lookup binary-searches the file for the password's hash.
+func (h *HashFile) Lookup(ctx context.Context, password string) (int, error) {
+	return h.lookupHash([]byte(h.typ.Hex(password)))
+}
+
+func (h *HashFile) lookupHash(target []byte) (int, error) {
+	// lo is always the start of a line and every line before it sorts
+	// below target; no line at or after hi can match.
+	lo, hi := int64(0), h.size
+	for hi-lo > scanWindow {
+		mid := lo + (hi-lo)/2
+		start, err := h.nextLineStart(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+		line, next, err := h.lineAt(start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		hash, count, ok := splitLine(line)
+		if !ok {
+			return 0, fmt.Errorf("%w: bad line at offset %d", ErrFormat, start)
+		}
+		switch c := bytes.Compare(hash, target); {
+		case c == 0:
+			return count, nil
+		case c < 0:
+			lo = next
+		default:
+			hi = start
+		}
+	}
+	// Read on from lo; the first line past hi sorts above target, so the
+	// scan stops there without cutting the last line short.
+	r := bufio.NewReader(io.NewSectionReader(h.f, lo, h.size-lo))
+	for {
+		line, err := r.ReadSlice('\n')
+		if len(line) > 0 {
+			hash, count, ok := splitLine(line)
+			if ok {
+				switch c := bytes.Compare(hash, target); {
+				case c == 0:
+					return count, nil
+				case c > 0:
+					return 0, nil
+				}
+			}
+		}
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// nextLineStart returns the offset of the first line starting after
+// off-1, i.e. off itself if a line starts there.
+func (h *HashFile) nextLineStart(off int64) (int64, error) {
+	if off == 0 {
+		return 0, nil
+	}
+	buf := make([]byte, 128)
+	for pos := off - 1; pos < h.size; pos += int64(len(buf)) {
+		n, err := h.f.ReadAt(buf, pos)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return pos + int64(i) + 1, nil
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return h.size, nil
+}
+
+// lineAt reads the line starting at off and returns it with the offset
+// of the following line.
+func (h *HashFile) lineAt(off int64) ([]byte, int64, error) {
+	buf := make([]byte, 128)
+	n, err := h.f.ReadAt(buf, off)
+	if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+		return buf[:i], off + int64(i) + 1, nil
+	}
+	if err == nil {
+		return nil, 0, fmt.Errorf("%w: line at offset %d is too long", ErrFormat, off)
+	}
+	return buf[:n], off + int64(n), err
+}
+
+// splitLine parses "HASH:COUNT", tolerating a trailing "\r".
+func splitLine(line []byte) (hash []byte, count int, ok bool) {
+	line = bytes.TrimRight(line, "\r\n")
+	hash, rest, found := bytes.Cut(line, []byte(":"))
+	if !found {
+		return nil, 0, false
+	}
+	count, err := strconv.Atoi(string(rest))
+	if err != nil {
+		return nil, 0, false
+	}
+	return hash, count, true
+}
@@ -0,0 +1,84 @@
+package breach
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultRangeURL is the public Pwned Passwords range API.
+const DefaultRangeURL = "https://api.pwnedpasswords.com"
+
+// RangeClient queries a k-anonymity range API: only the first five hex
+// digits of the hash are sent, and the server answers with the suffixes
+// of every hash sharing them. BaseURL may point at a local mirror or a
+// stand-in server that serves GET {BaseURL}/range/{prefix}.
+type RangeClient struct {
+	BaseURL string
+	Type    HashType
+	// Client defaults to one with a 10 second timeout.
+	Client *http.Client
+	// UserAgent is sent with every request, as the public API requires.
+	UserAgent string
+}
+
+var defaultRangeHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Lookup fetches the range for the password's hash prefix and returns the
+// count listed for its suffix. Responses are requested with padding so
+// their size does not hint at the prefix; padding entries have count 0.
+func (c *RangeClient) Lookup(ctx context.Context, password string) (int, error) {
+	hash := c.Type.Hex(password)
+	prefix, suffix := hash[:5], hash[5:]
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultRangeURL
+	}
+	endpoint := strings.TrimRight(base, "/") + "/range/" + prefix
+	if c.Type == NTLM {
+		endpoint += "?mode=ntlm"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, errors.New("range API: invalid base URL")
+	}
+	req.Header.Set("Add-Padding", "true")
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	client := c.Client
+	if client == nil {
+		client = defaultRangeHTTPClient
+	}
+	// Errors name only the host: the base URL may carry credentials or a
+	// token, and these errors are logged.
+	host := req.URL.Host
+	resp, err := client.Do(req)
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err // it quotes the whole URL
+		}
+		return 0, fmt.Errorf("range API %s: %w", host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("range API %s returned %s", host, resp.Status)
+	}
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		hash, count, ok := splitLine(sc.Bytes())
+		if ok && strings.EqualFold(string(hash), suffix) {
+			return count, nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return 0, fmt.Errorf("range API %s: %w", host, err)
+	}
+	return 0, nil
+}
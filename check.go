@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/strength"
+)
+
+// maxCheckLength bounds the passwords /api/check scores, in characters;
+// the search for the cheapest match sequence grows with the cube of it.
+const maxCheckLength = 256
+
+// checkRequest is the body of POST /api/check.
+type checkRequest struct {
+	Password string `json:"password"`
+	// UserInputs are words an attacker would try first for this user,
+	// such as their name or e-mail address.
+	UserInputs []string `json:"user_inputs,omitempty"`
+}
+
+// checkResponse is the strength estimate plus, when breach data is
+// configured, whether the password appears in it.
+type checkResponse struct {
+	strength.Result
+	Breach *breachReport `json:"breach,omitempty"`
+}
+
+type breachReport struct {
+	Found bool `json:"found"`
+	// Count is how often the password was seen; a Bloom filter reports 1.
+	Count int    `json:"count,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// checkHandler estimates the strength of a user-chosen password. Like
+// /api/derive, its input is a secret: it is never logged or echoed in
+// errors, and the response must not be cached.
+func checkHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAPIError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+		return
+	}
+	var req checkRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		// the decoder's message may quote the body, so keep it generic
+		writeAPIError(w, http.StatusBadRequest, errors.New("request body must be a JSON object with a password and optional user_inputs"))
+		return
+	}
+	if n := utf8.RuneCountInString(req.Password); n > maxCheckLength {
+		writeAPIError(w, http.StatusBadRequest, badParam("password", "must be at most %d characters, got %d", maxCheckLength, n))
+		return
+	}
+	res := checkPassword(r.Context(), req.Password, req.UserInputs)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		http.Error(w, "encode json: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// breachedGuessesLog10 caps the guesses for a password found in breach
+// data: attackers try breached passwords first, so it scores 0, and the
+// guesses and crack times have to agree with that score.
+const breachedGuessesLog10 = 3
+
+// checkPassword scores pwd against the loaded dictionaries and, when breach
+// data is configured, looks it up there. It is shared by /api/check and
+// the check command.
+func checkPassword(ctx context.Context, pwd string, userInputs []string) checkResponse {
+	res := checkResponse{Result: dicts.Load().checker.Check(pwd, userInputs...)}
+	if breachChecker != nil {
+		res.Breach = &breachReport{}
+		n, err := lookupBreach(ctx, pwd)
+		switch {
+		case err != nil:
+			log.Printf("Breach lookup failed: %v", err)
+			res.Breach.Error = "breach lookup failed"
+		case n > 0:
+			// Known to attackers, so however it is built it is guessable.
+			res.Breach.Found, res.Breach.Count = true, n
+			res.Result = res.Result.Capped(breachedGuessesLog10)
+			res.Feedback = strength.Feedback{
+				Warning:     "This password has appeared in a data breach",
+				Suggestions: []string{"Choose a password that has never been used before, on any site"},
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,742 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
+)
+
+// ------------------------------------------------------------
+// Command line
+// ------------------------------------------------------------
+
+// Exit codes, the same for every command.
+const (
+	exitOK    = 0
+	exitError = 1 // the command failed
+	exitUsage = 2 // invalid arguments, as with the flag package
+	exitWeak  = 3 // check: the password is too weak or has been breached
+)
+
+// command is one subcommand of the binary.
+type command struct {
+	name    string
+	args    string // shown after the name in the usage line
+	summary string
+	run     func(c command, args []string) int
+}
+
+var commands = []command{
+	{"serve", "[flags]", "Run the web interface and API (the default without a command)", runServe},
+	{"generate", "[flags]", "Print generated passwords", runGenerate},
+	{"check", "[flags] < password", "Estimate the strength of a password read from stdin", runCheck},
+	{"dict", "stats [flags]", "Show the loaded dictionaries", runDict},
+	{"cert", "[flags]", "Create the self-signed TLS certificate", runCert},
+	{"config", "print [flags]", "Show the effective configuration, with secrets redacted", runConfig},
+	{"build-bloom", "[flags] <hash file> <output>", "Build a Bloom filter from a Pwned Passwords hash file for PWOMATIC_BREACH_FILE", runBuildBloom},
+	{"version", "[flags]", "Show the build version, commit and Go version", runVersion},
+}
+
+// sampleCount is how many passwords the deprecated --sample prints.
+const sampleCount = 200
+
+// runCLI runs the command named by args[0] and returns the exit code.
+// Without a command, or with only flags, it serves, as the binary always
+// has. The old --sample switch still works as an alias of generate.
+func runCLI(args []string) int {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			if c, ok := lookupCommand(args[1]); ok {
+				return c.run(c, []string{"--help"})
+			}
+		}
+		usage(os.Stdout)
+		return exitOK
+	case "--sample", "-sample":
+		fmt.Fprintf(os.Stderr, "%s: --sample is deprecated; use \"%[1]s generate --count %d\"\n", progName(), sampleCount)
+		args = append([]string{"generate", "--count", strconv.Itoa(sampleCount)}, args[1:]...)
+	}
+	if strings.HasPrefix(args[0], "-") {
+		args = append([]string{"serve"}, args...)
+	}
+	c, ok := lookupCommand(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: unknown command %q\n\n", progName(), args[0])
+		usage(os.Stderr)
+		return exitUsage
+	}
+	return c.run(c, args[1:])
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func progName() string {
+	return filepath.Base(os.Args[0])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [command] [flags]\n\nCommands:\n", progName())
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, `
+Run "%s help <command>" for a command's flags.
+
+Exit status is %d on success, %d when the command fails, %d for invalid
+arguments and %d when check finds the password too weak.
+`, progName(), exitOK, exitError, exitUsage, exitWeak)
+}
+
+// flags returns an empty flag set for c whose usage names the command.
+func (c command) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s %s\n\n%s.\n", progName(), c.name, c.args, c.summary)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(fs.Output(), "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parse parses args into fs. --help prints the usage to stdout and a bad
+// flag prints the error and the usage to stderr; ok is then false and code
+// is the exit code.
+func parse(fs *flag.FlagSet, args []string) (code int, ok bool) {
+	fs.SetOutput(io.Discard)
+	err := fs.Parse(args)
+	switch {
+	case err == nil:
+		return exitOK, true
+	case errors.Is(err, flag.ErrHelp):
+		fs.SetOutput(os.Stdout)
+		fs.Usage()
+		return exitOK, false
+	default:
+		fs.SetOutput(os.Stderr)
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", progName(), fs.Name(), err)
+		fs.Usage()
+		return exitUsage, false
+	}
+}
+
+// usageError reports a problem with the arguments of fs's command.
+func usageError(fs *flag.FlagSet, format string, args ...any) int {
+	fmt.Fprintf(os.Stderr, "%s %s: %s\n", progName(), fs.Name(), fmt.Sprintf(format, args...))
+	return exitUsage
+}
+
+// fail prints err and returns its exit code: request errors, such as a
+// bad option or a policy the mode cannot satisfy, are usage errors.
+// Parameter errors name the flag rather than the API field.
+func fail(fs *flag.FlagSet, err error) int {
+	var pe *paramError
+	if errors.As(err, &pe) && pe.Field != "" {
+		return usageError(fs, "--%s: %s", strings.ReplaceAll(pe.Field, "_", "-"), pe.Message)
+	}
+	if errors.As(err, &pe) || generateErrorStatus(err) == http.StatusBadRequest {
+		return usageError(fs, "%v", err)
+	}
+	fmt.Fprintf(os.Stderr, "%s %s: %v\n", progName(), fs.Name(), err)
+	return exitError
+}
+
+// quiet silences the log unless verbose is set. Commands other than serve
+// report through their output and exit code instead.
+func quiet(verbose bool) {
+	if !verbose {
+		log.SetOutput(io.Discard)
+	}
+}
+
+// ------------------------------------------------------------
+// serve
+// ------------------------------------------------------------
+
+func runServe(c command, args []string) int {
+	fs := c.flags()
+	settings := addConfigFlags(fs)
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q", fs.Arg(0))
+	}
+	if err := setup(settings); err != nil {
+		log.Print(err)
+		return exitError
+	}
+	if err := runServer(); err != nil {
+		log.Print(err)
+		return exitError
+	}
+	return exitOK
+}
+
+// ------------------------------------------------------------
+// generate
+// ------------------------------------------------------------
+
+// generateFlags are the generate flags that set the API parameter of the
+// same name, with underscores for dashes. Their values are parsed by
+// parseParams, so they take exactly what /api/passwords takes.
+var generateFlags = []struct {
+	name, usage string
+	isBool      bool
+}{
+	{"dict", "`dictionary` to draw words from (default: the default dictionary)", false},
+	{"policy", "password `policy` (default: default)", false},
+	{"min-length", "override the policy's minimum `length`", false},
+	{"max-length", "override the policy's maximum `length`", false},
+	{"min-upper", "override the policy's minimum `count` of upper case letters", false},
+	{"max-upper", "override the policy's maximum `count` of upper case letters; 0 for none", false},
+	{"min-lower", "override the policy's minimum `count` of lower case letters", false},
+	{"max-lower", "override the policy's maximum `count` of lower case letters; 0 for none", false},
+	{"min-digit", "override the policy's minimum `count` of digits", false},
+	{"max-digit", "override the policy's maximum `count` of digits; 0 for none", false},
+	{"min-symbol", "override the policy's minimum `count` of symbols", false},
+	{"max-symbol", "override the policy's maximum `count` of symbols; 0 for none", false},
+	{"words", "passphrase: `number` of words, 4-10", false},
+	{"sep", "passphrase: `separator`, hyphen, space, dot or digit", false},
+	{"caps", "passphrase: `capitalisation`, none, first, upper or random", false},
+	{"digits", "pronounceable: `number` of digits", false},
+	{"symbols", "pronounceable: `number` of symbols", false},
+	{"bytes", "secret modes: `number` of random bytes, 8-1024", false},
+	{"prefix", "secret modes: `text` to prepend", false},
+	{"checksum", "secret modes: append a CRC32 checksum", true},
+	{"nopad", "secret modes: drop base32 padding", true},
+	{"pattern", "pattern: the `template`, e.g. C-C-d{4}-s{2}", false},
+	{"exclude-ambiguous", "leave out look-alike characters", true},
+	{"symbol-set", "restrict symbols to a preset (shell-safe, url-safe, xml-safe) or a list of `symbols`", false},
+	{"alphabet", "the complete list of allowed `characters`", false},
+}
+
+func runGenerate(c command, args []string) int {
+	fs := c.flags()
+	mode := fs.String("mode", "normal", "generation `mode`: "+strings.Join(modeNames(), ", "))
+	count := fs.Int("count", 1, fmt.Sprintf("`number` of passwords, 1-%d", maxStreamCount))
+	length := fs.Int("length", 0, "password `length`: the length of a pin or pronounceable password, or the policy's minimum and maximum length for the other modes")
+	format := fs.String("format", "plain", "output `format`: plain (one password per line), json or csv")
+	clipboard := fs.Bool("clipboard", false, "copy the output to the clipboard instead of printing it")
+	noNewline := fs.Bool("no-newline", false, "leave out the newline after the last password")
+	verbose := fs.Bool("verbose", false, "log how the dictionaries were loaded")
+	settings := addConfigFlags(fs)
+	strs := map[string]*string{}
+	bools := map[string]*bool{}
+	for _, f := range generateFlags {
+		if f.isBool {
+			bools[f.name] = fs.Bool(f.name, false, f.usage)
+		} else {
+			strs[f.name] = fs.String(f.name, "", f.usage)
+		}
+	}
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q", fs.Arg(0))
+	}
+	if _, ok := generator.Lookup(*mode); !ok {
+		return usageError(fs, "unknown mode %q (available: %s)", *mode, strings.Join(modeNames(), ", "))
+	}
+	if *count < 1 || *count > maxStreamCount {
+		return usageError(fs, "--count must be between 1 and %d, got %d", maxStreamCount, *count)
+	}
+	if *format != "plain" && *format != "json" && *format != "csv" {
+		return usageError(fs, "--format must be plain, json or csv, got %q", *format)
+	}
+
+	// Only the flags given are passed on, so that unset ones keep the
+	// defaults of the mode and policy.
+	form := url.Values{"mode": {*mode}}
+	fs.Visit(func(f *flag.Flag) {
+		name := strings.ReplaceAll(f.Name, "-", "_")
+		if p, ok := strs[f.Name]; ok {
+			form.Set(name, *p)
+		} else if b, ok := bools[f.Name]; ok && *b {
+			form.Set(name, "true")
+		}
+	})
+	if *length != 0 {
+		switch {
+		case *mode == "pin" || *mode == "pronounceable":
+			form.Set("length", strconv.Itoa(*length))
+		case !usesPolicyLength(*mode):
+			return usageError(fs, "--length does not apply to mode %s, whose length follows from its own options such as --words, --bytes or --pattern", *mode)
+		default:
+			if !form.Has("min_length") {
+				form.Set("min_length", strconv.Itoa(*length))
+			}
+			if !form.Has("max_length") {
+				form.Set("max_length", strconv.Itoa(*length))
+			}
+		}
+	}
+
+	quiet(*verbose)
+	if err := setup(settings); err != nil {
+		return fail(fs, err)
+	}
+	dicts.Store(startupDictionaries())
+	params, err := parseParams(form)
+	if err != nil {
+		return fail(fs, err)
+	}
+	g, err := params.generator()
+	if err != nil {
+		return fail(fs, err)
+	}
+
+	var out io.Writer = os.Stdout
+	var clip bytes.Buffer
+	if *clipboard {
+		out = &clip
+	}
+	buf := bufio.NewWriter(out)
+	var w io.Writer = buf
+	if *noNewline || *clipboard {
+		w = &holdNewline{w: buf}
+	}
+	if err := writePasswords(context.Background(), w, g, *mode, *count, *format); err != nil {
+		if degraded := dicts.Load().degraded; degraded != nil && errors.Is(err, generator.ErrNoWords) {
+			err = fmt.Errorf("%w: %v", err, degraded)
+		}
+		buf.Flush()
+		return fail(fs, err)
+	}
+	if err := buf.Flush(); err != nil {
+		return fail(fs, err)
+	}
+	if *clipboard {
+		if err := copyToClipboard(clip.String()); err != nil {
+			return fail(fs, err)
+		}
+		noun := "passwords"
+		if *count == 1 {
+			noun = "password"
+		}
+		fmt.Fprintf(os.Stderr, "Copied %d %s to the clipboard.\n", *count, noun)
+	}
+	return exitOK
+}
+
+// usesPolicyLength reports whether mode's passwords are held to the
+// policy's length bounds, which --length then sets. Modes that scope the
+// length out of the policy, such as passphrases and secrets, decide it
+// themselves.
+func usesPolicyLength(mode string) bool {
+	m, _ := generator.Lookup(mode)
+	s, ok := m.(generator.PolicyScoper)
+	return !ok || s.ScopePolicy(generator.DefaultPolicy).MaxLength > 0
+}
+
+// writePasswords generates count passwords with the same retry and
+// metrics accounting as the API and writes them to w as they are made: plain is one per line, json an array of the objects
+// /api/v2/generate returns and csv the rows of /api/stream.
+func writePasswords(ctx context.Context, w io.Writer, g *generator.Generator, mode string, count int, format string) error {
+	var cw *csv.Writer
+	switch format {
+	case "json":
+		io.WriteString(w, "[\n")
+	case "csv":
+		cw = csv.NewWriter(w)
+		cw.Write(streamCSVHeader)
+	}
+	for i := 1; i <= count; i++ {
+		res, err := generate(ctx, g, mode)
+		if err != nil {
+			return fmt.Errorf("could not generate password: %w", err)
+		}
+		switch format {
+		case "plain":
+			_, err = io.WriteString(w, res.Password+"\n")
+		case "json":
+			var line []byte
+			if line, err = json.Marshal(v2PasswordFrom(res)); err == nil {
+				if i > 1 {
+					line = append([]byte(",\n"), line...)
+				}
+				_, err = w.Write(line)
+			}
+		case "csv":
+			err = (&csvWriter{w: cw}).write(streamRow{ID: strconv.Itoa(i), Password: res.Password, Mode: res.Mode, Fallback: res.FellBack, Entropy: roundBits(res.Entropy)})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	switch format {
+	case "json":
+		_, err := io.WriteString(w, "\n]\n")
+		return err
+	case "csv":
+		cw.Flush()
+		return cw.Error()
+	}
+	return nil
+}
+
+// holdNewline writes through to w, except that a trailing newline is
+// held back until more output follows, so the last one is never written.
+type holdNewline struct {
+	w    io.Writer
+	held bool
+}
+
+func (h *holdNewline) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if h.held {
+		if _, err := h.w.Write([]byte{'\n'}); err != nil {
+			return 0, err
+		}
+		h.held = false
+	}
+	body := p
+	if p[len(p)-1] == '\n' {
+		body, h.held = p[:len(p)-1], true
+	}
+	if _, err := h.w.Write(body); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func modeNames() []string {
+	var names []string
+	for _, m := range generator.Modes() {
+		names = append(names, m.Name())
+	}
+	return names
+}
+
+// ------------------------------------------------------------
+// check
+// ------------------------------------------------------------
+
+// stringList is a flag that may be repeated.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// runCheck scores a password read from stdin, never from the arguments,
+// where it would end up in the shell history and the process list.
+func runCheck(c command, args []string) int {
+	fs := c.flags()
+	minScore := fs.Int("min-score", 3, "lowest acceptable `score`, 0-4; below it the exit status is 3")
+	var inputs stringList
+	fs.Var(&inputs, "user-input", "a `word` an attacker would try first, such as the user's name; may be repeated")
+	asJSON := fs.Bool("json", false, "print the full estimate as JSON, as /api/check returns it")
+	verbose := fs.Bool("verbose", false, "log how the dictionaries were loaded")
+	settings := addConfigFlags(fs)
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "the password is read from stdin, not the arguments")
+	}
+	if *minScore < 0 || *minScore > 4 {
+		return usageError(fs, "--min-score must be between 0 and 4, got %d", *minScore)
+	}
+
+	if st, err := os.Stdin.Stat(); err == nil && st.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fail(fs, fmt.Errorf("read password: %w", err))
+	}
+	pwd := strings.TrimRight(line, "\r\n")
+	if pwd == "" {
+		return usageError(fs, "no password on stdin")
+	}
+	if n := utf8.RuneCountInString(pwd); n > maxCheckLength {
+		return usageError(fs, "the password must be at most %d characters, got %d", maxCheckLength, n)
+	}
+
+	quiet(*verbose)
+	if err := setup(settings); err != nil {
+		return fail(fs, err)
+	}
+	dicts.Store(startupDictionaries())
+	res := checkPassword(context.Background(), pwd, inputs)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			return fail(fs, err)
+		}
+	} else {
+		printCheck(os.Stdout, res)
+	}
+	if res.Score < *minScore || (res.Breach != nil && res.Breach.Found) {
+		return exitWeak
+	}
+	return exitOK
+}
+
+func printCheck(w io.Writer, res checkResponse) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Score:\t%d of 4\n", res.Score)
+	fmt.Fprintf(tw, "Guesses:\t10^%.1f\n", res.GuessesLog10)
+	fmt.Fprintf(tw, "Online, throttled:\t%s\n", res.CrackTimesDisplay.OnlineThrottling)
+	fmt.Fprintf(tw, "Online, unthrottled:\t%s\n", res.CrackTimesDisplay.OnlineNoThrottling)
+	fmt.Fprintf(tw, "Offline, slow hash:\t%s\n", res.CrackTimesDisplay.OfflineSlowHashing)
+	fmt.Fprintf(tw, "Offline, fast hash:\t%s\n", res.CrackTimesDisplay.OfflineFastHashing)
+	if b := res.Breach; b != nil {
+		switch {
+		case b.Error != "":
+			fmt.Fprintf(tw, "Breached:\tunknown, %s\n", b.Error)
+		case b.Found:
+			fmt.Fprintf(tw, "Breached:\tyes, seen %d times\n", b.Count)
+		default:
+			fmt.Fprintf(tw, "Breached:\tno\n")
+		}
+	}
+	if res.Feedback.Warning != "" {
+		fmt.Fprintf(tw, "Warning:\t%s\n", res.Feedback.Warning)
+	}
+	for i, s := range res.Feedback.Suggestions {
+		label := ""
+		if i == 0 {
+			label = "Suggestions:"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", label, s)
+	}
+	tw.Flush()
+}
+
+// ------------------------------------------------------------
+// dict
+// ------------------------------------------------------------
+
+func runDict(c command, args []string) int {
+	fs := c.flags()
+	asJSON := fs.Bool("json", false, "print the status as JSON, as /api/status returns it")
+	verbose := fs.Bool("verbose", false, "log how the dictionaries were loaded")
+	settings := addConfigFlags(fs)
+	if len(args) == 0 || args[0] != "stats" {
+		if code, ok := parse(fs, args); !ok {
+			return code
+		}
+		if len(args) == 0 {
+			return usageError(fs, "missing subcommand; the only one is stats")
+		}
+		return usageError(fs, "unknown subcommand %q; the only one is stats", args[0])
+	}
+	if code, ok := parse(fs, args[1:]); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q", fs.Arg(0))
+	}
+
+	quiet(*verbose)
+	if err := setup(settings); err != nil {
+		return fail(fs, err)
+	}
+	dicts.Store(startupDictionaries())
+	st := currentStatus()
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(st); err != nil {
+			return fail(fs, err)
+		}
+	} else {
+		printDictStats(os.Stdout, st)
+	}
+	if st.Degraded {
+		return exitError
+	}
+	return exitOK
+}
+
+func printDictStats(w io.Writer, st dictStatus) {
+	source := st.Source
+	if st.Path != "" {
+		source += " " + st.Path
+	}
+	fmt.Fprintf(w, "Source: %s\n", source)
+	if st.Degraded {
+		fmt.Fprintf(w, "No dictionary could be loaded: %s\n", st.Error)
+		return
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tLABEL\tWORDS\tBITS/WORD\tDICEWARE\tDROPPED")
+	for _, d := range st.Dictionaries {
+		name := d.Name
+		if d.Default {
+			name += " *"
+		}
+		diceware := "no"
+		if d.Diceware {
+			diceware = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.1f\t%s\t%d\n", name, d.Label, d.Words, d.WordBits, diceware, d.Dropped.total())
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "\n* default")
+	for _, d := range st.Dictionaries {
+		if d.Dropped.total() > 0 {
+			fmt.Fprintf(w, "%s dropped %s.\n", d.Name, d.Dropped)
+		}
+	}
+}
+
+// ------------------------------------------------------------
+// cert
+// ------------------------------------------------------------
+
+func runCert(c command, args []string) int {
+	fs := c.flags()
+	force := fs.Bool("force", false, "replace an existing certificate and key")
+	settings := addConfigFlags(fs)
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q", fs.Arg(0))
+	}
+	if err := configure(settings); err != nil {
+		return fail(fs, err)
+	}
+	if certExists() && !*force {
+		fmt.Fprintf(os.Stderr, "%s and %s already exist; use --force to replace them.\n", cfg.CertFile, cfg.KeyFile)
+		return exitOK
+	}
+	if err := writeSelfSignedCert(); err != nil {
+		return fail(fs, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s and %s.\n", cfg.CertFile, cfg.KeyFile)
+	return exitOK
+}
+
+// ------------------------------------------------------------
+// config
+// ------------------------------------------------------------
+
+func runConfig(c command, args []string) int {
+	fs := c.flags()
+	settings := addConfigFlags(fs)
+	if len(args) == 0 || args[0] != "print" {
+		if code, ok := parse(fs, args); !ok {
+			return code
+		}
+		if len(args) == 0 {
+			return usageError(fs, "missing subcommand; the only one is print")
+		}
+		return usageError(fs, "unknown subcommand %q; the only one is print", args[0])
+	}
+	if code, ok := parse(fs, args[1:]); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q", fs.Arg(0))
+	}
+	// An invalid configuration is still printed, to help find the mistake.
+	conf, err := loadConfig(settings)
+	if conf.sources != nil {
+		if perr := conf.print(os.Stdout); perr != nil {
+			return fail(fs, perr)
+		}
+	}
+	if err != nil {
+		return fail(fs, fmt.Errorf("configuration: %w", err))
+	}
+	return exitOK
+}
+
+// ------------------------------------------------------------
+// build-bloom
+// ------------------------------------------------------------
+
+func runBuildBloom(c command, args []string) int {
+	fs := c.flags()
+	fp := fs.Float64("fp", 0.001, "false-positive `rate`")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 2 {
+		return usageError(fs, "need a hash file and an output file")
+	}
+	if err := buildBloomFile(fs.Arg(0), fs.Arg(1), *fp); err != nil {
+		return fail(fs, fmt.Errorf("build Bloom filter: %w", err))
+	}
+	return exitOK
+}
+
+// ------------------------------------------------------------
+// version
+// ------------------------------------------------------------
+
+func runVersion(c command, args []string) int {
+	fs := c.flags()
+	asJSON := fs.Bool("json", false, "print JSON, as served on /version")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q", fs.Arg(0))
+	}
+	info := readBuildInfo()
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(info); err != nil {
+			return fail(fs, err)
+		}
+		return exitOK
+	}
+	fmt.Printf("%s %s\n", progName(), info.Version)
+	if info.Commit != "" {
+		modified := ""
+		if info.Modified {
+			modified = " (modified)"
+		}
+		fmt.Printf("commit %s%s %s\n", info.Commit, modified, info.CommitTime)
+	}
+	fmt.Println(info.GoVersion)
+	return exitOK
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// clipboardCommands are the programs tried, in order, to put text on the
+// clipboard. One that needs a display server is skipped unless env is set.
+var clipboardCommands = []struct {
+	env  string
+	args []string
+}{
+	{"", []string{"pbcopy"}}, // macOS
+	{"WAYLAND_DISPLAY", []string{"wl-copy"}},
+	{"DISPLAY", []string{"xclip", "-selection", "clipboard"}},
+	{"DISPLAY", []string{"xsel", "--clipboard", "--input"}},
+	{"", []string{"clip.exe"}}, // Windows and WSL
+}
+
+// copyToClipboard puts text on the system clipboard using the first
+// clipboard program available.
+func copyToClipboard(text string) error {
+	for _, c := range clipboardCommands {
+		if c.env != "" && os.Getenv(c.env) == "" {
+			continue
+		}
+		path, err := exec.LookPath(c.args[0])
+		if err != nil {
+			continue
+		}
+		// xclip and wl-copy stay behind to serve the selection, so their
+		// output must not be a pipe that Run would wait on.
+		cmd := exec.Command(path, c.args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", c.args[0], err)
+		}
+		return nil
+	}
+	return errors.New("no clipboard program found (tried pbcopy, wl-copy, xclip, xsel and clip.exe)")
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/breach"
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
+)
+
+// ------------------------------------------------------------
+// Configuration
+// ------------------------------------------------------------
+
+// configFile is read from the working directory when neither --config nor
+// PWOMATIC_CONFIG names another file. It is optional.
+const configFile = "password-o-matic.yaml"
+
+// config holds every setting. Each one is taken from, in increasing
+// priority, its default, the config file, the environment variable
+// PWOMATIC_<KEY> and the flag --<key> (with dashes for underscores).
+type config struct {
+	Listen   string `yaml:"listen" toml:"listen"`
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+
+	// Server timeouts; 0 means none. ShutdownTimeout bounds how long a
+	// stopping server waits for requests in flight.
+	ReadHeaderTimeout duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout   duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+
+	DictDir   string `yaml:"dict_dir" toml:"dict_dir"`
+	DictFile  string `yaml:"dict_file" toml:"dict_file"`
+	PolicyDir string `yaml:"policy_dir" toml:"policy_dir"`
+
+	// The default policy's length bounds and symbol set.
+	PolicyMinLength int    `yaml:"policy_min_length" toml:"policy_min_length"`
+	PolicyMaxLength int    `yaml:"policy_max_length" toml:"policy_max_length"`
+	PolicySymbols   string `yaml:"policy_symbols" toml:"policy_symbols"`
+
+	MaxCount int `yaml:"max_count" toml:"max_count"`
+
+	BreachFile string `yaml:"breach_file" toml:"breach_file"`
+	BreachAPI  string `yaml:"breach_api" toml:"breach_api"`
+	BreachHash string `yaml:"breach_hash" toml:"breach_hash"`
+
+	// file is the config file read, if any, and sources maps each key
+	// that did not keep its default to where its value came from.
+	file    string
+	sources map[string]string
+}
+
+func defaultConfig() config {
+	return config{
+		Listen:   ":8443",
+		CertFile: "cert.pem",
+		KeyFile:  "key.pem",
+
+		// ReadHeaderTimeout is what stops slowloris clients.
+		ReadHeaderTimeout: duration(10 * time.Second),
+		ReadTimeout:       duration(30 * time.Second),
+		WriteTimeout:      duration(60 * time.Second),
+		IdleTimeout:       duration(2 * time.Minute),
+		// below the 30 second grace period Kubernetes gives a pod
+		ShutdownTimeout: duration(25 * time.Second),
+
+		DictDir:         "dictionaries",
+		DictFile:        "dictionary.txt",
+		PolicyDir:       "policies",
+		PolicyMinLength: generator.MinLength,
+		PolicyMaxLength: generator.MaxLength,
+		PolicySymbols:   generator.Symbols,
+		MaxCount:        defaultMaxCount,
+		BreachHash:      "sha1",
+	}
+}
+
+// configField is one setting of a config, for the layers that address
+// settings by key.
+type configField struct {
+	key, usage string
+	// Exactly one of str, num and dur points into the config.
+	str *string
+	num *int
+	dur *duration
+	// redact, if set, hides the secret parts of the value in config print.
+	redact func(string) string
+}
+
+func (c *config) fields() []configField {
+	return []configField{
+		{key: "listen", usage: "`address` to serve on, host:port", str: &c.Listen},
+		{key: "cert_file", usage: "TLS certificate `file`", str: &c.CertFile},
+		{key: "key_file", usage: "TLS key `file`", str: &c.KeyFile},
+		{key: "read_header_timeout", usage: "`time` allowed to read request headers", dur: &c.ReadHeaderTimeout},
+		{key: "read_timeout", usage: "`time` allowed to read a whole request", dur: &c.ReadTimeout},
+		{key: "write_timeout", usage: "`time` allowed to write a response; streams extend it while they make progress", dur: &c.WriteTimeout},
+		{key: "idle_timeout", usage: "`time` an idle keep-alive connection stays open", dur: &c.IdleTimeout},
+		{key: "shutdown_timeout", usage: "`time` to let requests finish after SIGTERM or SIGINT", dur: &c.ShutdownTimeout},
+		{key: "dict_dir", usage: "`directory` of named word lists", str: &c.DictDir},
+		{key: "dict_file", usage: "single word list `file`, used without dict_dir", str: &c.DictFile},
+		{key: "policy_dir", usage: "`directory` of extra policies", str: &c.PolicyDir},
+		{key: "policy_min_length", usage: "minimum `length` of the default policy", num: &c.PolicyMinLength},
+		{key: "policy_max_length", usage: "maximum `length` of the default policy", num: &c.PolicyMaxLength},
+		{key: "policy_symbols", usage: "`symbols` the default policy allows", str: &c.PolicySymbols},
+		{key: "max_count", usage: "largest `count` /api/passwords serves", num: &c.MaxCount},
+		{key: "breach_file", usage: "Pwned Passwords hash `file` or Bloom filter", str: &c.BreachFile},
+		{key: "breach_api", usage: "base `URL` of a k-anonymity range API", str: &c.BreachAPI, redact: redactURL},
+		{key: "breach_hash", usage: "`hash` of the range API, sha1 or ntlm", str: &c.BreachHash},
+	}
+}
+
+func (f configField) value() string {
+	switch {
+	case f.num != nil:
+		return strconv.Itoa(*f.num)
+	case f.dur != nil:
+		return f.dur.String()
+	}
+	return *f.str
+}
+
+func (f configField) set(v string) error {
+	switch {
+	case f.num != nil:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		*f.num = n
+	case f.dur != nil:
+		return f.dur.parse(v)
+	default:
+		*f.str = v
+	}
+	return nil
+}
+
+// duration is a time.Duration written like "30s". yaml.v3 would read a
+// bare number as nanoseconds, which is never what a config file means.
+// The TOML decoder goes through UnmarshalText.
+type duration time.Duration
+
+func (d duration) String() string { return time.Duration(d).String() }
+
+func (d *duration) parse(v string) error {
+	t, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration such as 30s", v)
+	}
+	*d = duration(t)
+	return nil
+}
+
+func (d *duration) UnmarshalYAML(n *yaml.Node) error {
+	if err := d.parse(n.Value); err != nil {
+		return fmt.Errorf("line %d: %w", n.Line, err)
+	}
+	return nil
+}
+
+func (d *duration) UnmarshalText(b []byte) error { return d.parse(string(b)) }
+
+func (f configField) envName() string {
+	return "PWOMATIC_" + strings.ToUpper(f.key)
+}
+
+func (f configField) flagName() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+// redactURL hides the password of a URL's user info.
+func redactURL(v string) string {
+	u, err := url.Parse(v)
+	if err != nil {
+		return "REDACTED"
+	}
+	return u.Redacted()
+}
+
+// configFlags are the flags of a command that loads the configuration:
+// --config and one flag per setting.
+type configFlags struct {
+	fs   *flag.FlagSet
+	path *string
+	vals map[string]*string
+}
+
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	cf := &configFlags{
+		fs:   fs,
+		path: fs.String("config", "", "configuration `file` (default $PWOMATIC_CONFIG, or "+configFile+" if it exists)"),
+		vals: map[string]*string{},
+	}
+	def := defaultConfig()
+	for _, f := range def.fields() {
+		usage := f.usage
+		if v := f.value(); v != "" {
+			usage += fmt.Sprintf(" (default %q)", v)
+		}
+		cf.vals[f.key] = fs.String(f.flagName(), "", usage)
+	}
+	return cf
+}
+
+// loadConfig layers the config file, the environment and flags, which may
+// be nil, over the defaults and validates the result.
+func loadConfig(flags *configFlags) (config, error) {
+	c := defaultConfig()
+	c.sources = map[string]string{}
+
+	path, explicit := configFile, false
+	if v := os.Getenv("PWOMATIC_CONFIG"); v != "" {
+		path, explicit = v, true
+	}
+	if flags != nil && *flags.path != "" {
+		path, explicit = *flags.path, true
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && !explicit:
+	case err != nil:
+		return config{}, err
+	default:
+		if err := c.decode(data, path); err != nil {
+			return config{}, fmt.Errorf("%s: %w", path, err)
+		}
+		c.file = path
+	}
+
+	for _, f := range c.fields() {
+		if v := os.Getenv(f.envName()); v != "" {
+			if err := f.set(v); err != nil {
+				return config{}, fmt.Errorf("%s: %w", f.envName(), err)
+			}
+			c.sources[f.key] = f.envName()
+		}
+	}
+
+	if flags != nil {
+		given := map[string]bool{}
+		flags.fs.Visit(func(fl *flag.Flag) { given[fl.Name] = true })
+		for _, f := range c.fields() {
+			if !given[f.flagName()] {
+				continue
+			}
+			if err := f.set(*flags.vals[f.key]); err != nil {
+				return config{}, fmt.Errorf("--%s: %w", f.flagName(), err)
+			}
+			c.sources[f.key] = "--" + f.flagName()
+		}
+	}
+	return c, c.validate()
+}
+
+// decode reads the config file, TOML if its name ends in .toml and YAML
+// otherwise, recording which keys it sets. Unknown keys are an error, so
+// that a typo does not go unnoticed.
+func (c *config) decode(data []byte, path string) error {
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		return c.decodeTOML(data, path)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && err != io.EOF {
+		return err
+	}
+	var keys map[string]any
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	for key := range keys {
+		c.sources[key] = path
+	}
+	return nil
+}
+
+func (c *config) decodeTOML(data []byte, path string) error {
+	md, err := toml.NewDecoder(bytes.NewReader(data)).Decode(c)
+	if err != nil {
+		return err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf("unknown key %q", undecoded[0].String())
+	}
+	for _, key := range md.Keys() {
+		c.sources[key.String()] = path
+	}
+	return nil
+}
+
+// source returns where key's value came from.
+func (c config) source(key string) string {
+	if s, ok := c.sources[key]; ok {
+		return s
+	}
+	return "default"
+}
+
+func (c config) validate() error {
+	// from names the setting and, unless it is the default, its source.
+	from := func(key string) string {
+		if s := c.source(key); s != "default" {
+			return key + " (from " + s + ")"
+		}
+		return key
+	}
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		return fmt.Errorf("%s: %w", from("listen"), err)
+	}
+	for _, f := range c.fields() {
+		if f.str != nil && *f.str == "" && !strings.HasPrefix(f.key, "breach_") {
+			return fmt.Errorf("%s must not be empty", from(f.key))
+		}
+	}
+	if strings.IndexFunc(c.PolicySymbols, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r)
+	}) >= 0 {
+		return fmt.Errorf("%s may only hold symbols, got %q", from("policy_symbols"), c.PolicySymbols)
+	}
+	if c.PolicyMaxLength > maxLengthLimit {
+		return fmt.Errorf("%s must be at most %d, got %d", from("policy_max_length"), maxLengthLimit, c.PolicyMaxLength)
+	}
+	if err := c.defaultPolicy().Validate(); err != nil {
+		return fmt.Errorf("policy_min_length, policy_max_length and policy_symbols: %v", err)
+	}
+	for _, f := range c.fields() {
+		if f.dur != nil && *f.dur < 0 {
+			return fmt.Errorf("%s must not be negative, got %s", from(f.key), f.dur)
+		}
+	}
+	if c.MaxCount < 1 {
+		return fmt.Errorf("%s must be a positive number, got %d", from("max_count"), c.MaxCount)
+	}
+	if _, err := breach.ParseHashType(c.BreachHash); err != nil {
+		return fmt.Errorf("%s: %w", from("breach_hash"), err)
+	}
+	if c.BreachAPI != "" {
+		u, err := url.Parse(c.BreachAPI)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s must be an http or https URL", from("breach_api"))
+		}
+	}
+	return nil
+}
+
+// defaultPolicy is generator.DefaultPolicy with the configured length
+// bounds and symbols.
+func (c config) defaultPolicy() generator.Policy {
+	p := generator.DefaultPolicy
+	p.MinLength, p.MaxLength, p.Symbols = c.PolicyMinLength, c.PolicyMaxLength, c.PolicySymbols
+	return p
+}
+
+// configure loads the configuration into cfg.
+func configure(flags *configFlags) error {
+	c, err := loadConfig(flags)
+	if err != nil {
+		return fmt.Errorf("configuration: %w", err)
+	}
+	cfg = c
+	return nil
+}
+
+// print writes the configuration as YAML, each setting commented with
+// where it came from and secrets redacted.
+func (c config) print(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	if c.file != "" {
+		doc.HeadComment = "Config file: " + c.file
+	}
+	for _, f := range c.fields() {
+		v := f.value()
+		if f.redact != nil && v != "" {
+			v = f.redact(v)
+		}
+		val := &yaml.Node{Kind: yaml.ScalarNode, Value: v, LineComment: c.source(f.key)}
+		if f.num != nil {
+			val.Tag = "!!int"
+		} else {
+			val.Tag = "!!str"
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: f.key}, val)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigFile(t *testing.T) {
+	files := []struct{ name, body string }{
+		{"pwomatic.yaml", "listen: :9443\nread_timeout: 5s\nmax_count: 50\n"},
+		{"pwomatic.toml", "listen = \":9443\"\nread_timeout = \"5s\"\nmax_count = 50\n"},
+	}
+	for _, f := range files {
+		path := filepath.Join(t.TempDir(), f.name)
+		if err := os.WriteFile(path, []byte(f.body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("PWOMATIC_CONFIG", path)
+		c, err := loadConfig(nil)
+		if err != nil {
+			t.Fatalf("%s: %v", f.name, err)
+		}
+		if c.Listen != ":9443" || time.Duration(c.ReadTimeout) != 5*time.Second || c.MaxCount != 50 {
+			t.Errorf("%s: got listen %q, read_timeout %v, max_count %d", f.name, c.Listen, c.ReadTimeout, c.MaxCount)
+		}
+		for _, key := range []string{"listen", "read_timeout", "max_count"} {
+			if s := c.source(key); s != path {
+				t.Errorf("%s: %s comes from %q, want the file", f.name, key, s)
+			}
+		}
+		if s := c.source("cert_file"); s != "default" {
+			t.Errorf("%s: cert_file comes from %q, want default", f.name, s)
+		}
+	}
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	cases := []struct{ name, body, want string }{
+		{"typo.yaml", "listen_port: 3\n", "listen_port"},
+		{"typo.toml", "listen_port = 3\n", `unknown key "listen_port"`},
+		{"table.toml", "[server]\nlisten = \":9443\"\n", `unknown key "server"`},
+		{"duration.yaml", "read_timeout: 30\n", "not a duration"},
+		{"duration.toml", "read_timeout = 30\n", "not a duration"},
+		{"syntax.toml", "listen = \n", "line 1"},
+	}
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), c.name)
+		if err := os.WriteFile(path, []byte(c.body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("PWOMATIC_CONFIG", path)
+		if _, err := loadConfig(nil); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %v, want an error containing %q", c.name, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
+)
+
+// deriveSlots bounds concurrent Argon2id runs; each one takes 64 MiB.
+var deriveSlots = make(chan struct{}, 4)
+
+// deriveRequest is the body of POST /api/derive.
+type deriveRequest struct {
+	Master  string `json:"master"`
+	Site    string `json:"site"`
+	Login   string `json:"login"`
+	Counter int    `json:"counter"`
+	Mode    string `json:"mode"`
+	Dict    string `json:"dict"`
+	Policy  string `json:"policy"`
+}
+
+// deriveHandler returns the deterministic password for a master passphrase,
+// site, login and counter. Its inputs are secrets: they are never logged,
+// never echoed in errors, and the response must not be cached.
+func deriveHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	var req deriveRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		// the decoder's message may quote the body, so keep it generic
+		http.Error(w, "request body must be a JSON object with master, site, login, counter and mode", http.StatusBadRequest)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = "normal"
+	}
+	if req.Counter == 0 {
+		req.Counter = 1
+	}
+	if _, ok := generator.Lookup(req.Mode); !ok {
+		http.Error(w, "unknown mode", http.StatusBadRequest)
+		return
+	}
+	if _, ok := dicts.Load().generator(req.Dict); !ok {
+		http.Error(w, "unknown dictionary", http.StatusBadRequest)
+		return
+	}
+	g, err := generatorFor(req.Dict, req.Policy)
+	if err != nil {
+		http.Error(w, "unknown policy", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case deriveSlots <- struct{}{}:
+		defer func() { <-deriveSlots }()
+	case <-r.Context().Done():
+		return
+	}
+	res, err := g.Derive(req.Mode, generator.DeriveParams{
+		Master:  req.Master,
+		Site:    req.Site,
+		Login:   req.Login,
+		Counter: req.Counter,
+	})
+	if err != nil {
+		http.Error(w, "Could not derive password: "+err.Error(), generateErrorStatus(err))
+		return
+	}
+	resp := map[string]interface{}{
+		"password": res.Password,
+		"mode":     res.Mode,
+		"fallback": res.FellBack,
+		"entropy":  roundBits(res.Entropy),
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "encode json: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
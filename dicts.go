@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
+	"github.com/Yoshiofthewire/Password-O-Matic/strength"
+)
+
+// ------------------------------------------------------------
+// Named dictionaries
+// ------------------------------------------------------------
+
+const (
+	// dictManifest, inside cfg.DictDir, optionally sets labels, word minimums,
+	// hygiene options and the default dictionary.
+	dictManifest = "dictionaries.yaml"
+	// dictBlocklist, inside cfg.DictDir, lists words dropped from every list.
+	dictBlocklist = "blocklist.txt"
+	// defaultMinWords is the smallest list accepted unless the manifest
+	// says otherwise.
+	defaultMinWords = 10000
+)
+
+// effLargeWordlist is the EFF large diceware list, served when neither
+// cfg.DictDir nor cfg.DictFile exists.
+//
+//go:embed wordlists/eff_large_wordlist.txt
+var effLargeWordlist []byte
+
+// effLargeWords is one word per roll of five dice.
+const effLargeWords = 6 * 6 * 6 * 6 * 6
+
+// Where the dictionaries came from, as reported by /api/status.
+const (
+	sourceDirectory = "directory"
+	sourceFile      = "file"
+	sourceEmbedded  = "embedded"
+	sourceNone      = "none"
+)
+
+// dictionary is one loaded word list and the generator built from it.
+type dictionary struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Words int    `json:"words"`
+	// WordBits is the entropy of one word drawn from the whole list.
+	WordBits float64 `json:"word_bits"`
+	MinWords int     `json:"min_words"`
+	Diceware bool    `json:"diceware"`
+	Default  bool    `json:"default"`
+	// Dropped is what the hygiene pipeline removed from the file.
+	Dropped dropReport `json:"dropped"`
+
+	words []string
+	gen   *generator.Generator
+}
+
+// newDictionary cleans words, checks what is left and builds their
+// generator. blocked may be nil.
+func newDictionary(name string, opt dictOption, words, dice []string, blocked map[string]bool) (*dictionary, error) {
+	if err := checkEncoding(words); err != nil {
+		return nil, fmt.Errorf("dictionary %q: %w", name, err)
+	}
+	h := opt.hygiene.or(defaultHygiene)
+	if err := h.validate(); err != nil {
+		return nil, fmt.Errorf("dictionary %q: %w", name, err)
+	}
+	words, dice, dropped := h.clean(words, dice, blocked)
+	if len(words) < opt.MinWords {
+		return nil, fmt.Errorf("dictionary %q contains only %d usable words – at least %d required (dropped %s)", name, len(words), opt.MinWords, dropped)
+	}
+	label := opt.Label
+	if label == "" {
+		label = name
+	}
+	return &dictionary{
+		Name:     name,
+		Label:    label,
+		Words:    len(words),
+		WordBits: roundBits(math.Log2(float64(max(len(words), 1)))),
+		MinWords: opt.MinWords,
+		Diceware: dice != nil,
+		Dropped:  dropped,
+		words:    words,
+		gen:      newGenerator(words, dice),
+	}, nil
+}
+
+// newGenerator builds the generator for one word list; words may be empty.
+func newGenerator(words, dice []string) *generator.Generator {
+	g := generator.New(words)
+	g.Dice = dice
+	if breachChecker != nil {
+		g.Screen = screenBreached
+	}
+	return g
+}
+
+// dictSet is every dictionary a request may pick with ?dict=<name>. It is
+// never modified once built; a reload swaps in a new one.
+type dictSet struct {
+	byName map[string]*dictionary
+	names  []string // sorted
+	def    string
+	// checker scores passwords against the words of every dictionary.
+	checker *strength.Estimator
+
+	// source is where the lists came from, path the directory or file
+	// for sourceDirectory and sourceFile.
+	source, path string
+	loadedAt     time.Time
+	// degraded is why no list could be loaded. The set then has no
+	// dictionaries and wordless serves the modes that need none.
+	degraded error
+	wordless *generator.Generator
+}
+
+// newDictSet indexes list. def names the default; when empty, "en" is
+// preferred and otherwise the first name in order.
+func newDictSet(list []*dictionary, def string) (*dictSet, error) {
+	if len(list) == 0 {
+		return nil, errors.New("no dictionaries loaded")
+	}
+	s := &dictSet{byName: make(map[string]*dictionary, len(list)), loadedAt: time.Now()}
+	for _, d := range list {
+		s.byName[d.Name] = d
+		s.names = append(s.names, d.Name)
+	}
+	sort.Strings(s.names)
+	switch {
+	case def != "":
+		if _, ok := s.byName[def]; !ok {
+			return nil, fmt.Errorf("default dictionary %q is not loaded", def)
+		}
+	case s.byName["en"] != nil:
+		def = "en"
+	default:
+		def = s.names[0]
+	}
+	s.def = def
+	s.byName[def].Default = true
+	s.checker = strength.New(s.allWords())
+	return s, nil
+}
+
+// degradedDictSet serves without any word list after err: the modes that
+// need words fail with generator.ErrNoWords, the others work as usual.
+func degradedDictSet(err error) *dictSet {
+	return &dictSet{
+		byName:   map[string]*dictionary{},
+		checker:  strength.New(nil),
+		source:   sourceNone,
+		loadedAt: time.Now(),
+		degraded: err,
+		wordless: newGenerator(nil, nil),
+	}
+}
+
+// generator returns the base generator of the named dictionary; an empty
+// name is the default.
+func (s *dictSet) generator(name string) (*generator.Generator, bool) {
+	if name == "" && s.degraded != nil {
+		return s.wordless, true
+	}
+	d, ok := s.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return d.gen, true
+}
+
+// lookup returns the named dictionary; an empty name is the default.
+func (s *dictSet) lookup(name string) (*dictionary, bool) {
+	if name == "" {
+		name = s.def
+	}
+	d, ok := s.byName[name]
+	return d, ok
+}
+
+// list returns the dictionaries in name order.
+func (s *dictSet) list() []*dictionary {
+	out := make([]*dictionary, 0, len(s.names))
+	for _, name := range s.names {
+		out = append(out, s.byName[name])
+	}
+	return out
+}
+
+// allWords joins every dictionary, for the strength checker.
+func (s *dictSet) allWords() []string {
+	var words []string
+	for _, d := range s.list() {
+		words = append(words, d.words...)
+	}
+	return words
+}
+
+// dicts is loaded at startup and replaced as a whole by reloads.
+var dicts atomic.Pointer[dictSet]
+
+// dictConfig is the optional manifest in cfg.DictDir:
+//
+//	default: en
+//	min_words: 10000
+//	max_length: 10
+//	dictionaries:
+//	  en: {label: English, allowed_chars: abcdefghijklmnopqrstuvwxyz}
+//	  eff-short: {label: EFF short list, min_words: 1296}
+//
+// The hygiene options set at the top apply to every list that does not
+// set its own.
+type dictConfig struct {
+	Default      string `yaml:"default"`
+	MinWords     int    `yaml:"min_words"`
+	hygiene      `yaml:",inline"`
+	Dictionaries map[string]dictOption `yaml:"dictionaries"`
+}
+
+type dictOption struct {
+	Label    string `yaml:"label"`
+	MinWords int    `yaml:"min_words"`
+	hygiene  `yaml:",inline"`
+}
+
+// startupDictionaries loads the dictionaries, or falls back to a degraded
+// set so that the modes without words stay available. Fixing the files
+// then brings the lists back through a reload.
+func startupDictionaries() *dictSet {
+	set, err := loadDictionaries()
+	if err != nil {
+		log.Printf("No dictionary available, serving only the modes without words: %v", err)
+		return degradedDictSet(err)
+	}
+	return set
+}
+
+// loadDictionaries reads every word list in cfg.DictDir, one per .txt file
+// named after the file ("de.txt" is dict=de). Without that
+// directory the single cfg.DictFile is loaded as dictionary "default", and
+// without that the embedded EFF large list as "eff-large".
+func loadDictionaries() (*dictSet, error) {
+	entries, err := os.ReadDir(cfg.DictDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return loadSingleDictionary()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest dictConfig
+	data, err := os.ReadFile(filepath.Join(cfg.DictDir, dictManifest))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&manifest); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("%s: %w", dictManifest, err)
+		}
+	}
+	if manifest.MinWords == 0 {
+		manifest.MinWords = defaultMinWords
+	}
+	blocked, err := loadBlocklist(filepath.Join(cfg.DictDir, dictBlocklist))
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*dictionary
+	found := map[string]bool{}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".txt")
+		if !ok || name == "" || e.IsDir() || e.Name() == dictBlocklist {
+			continue
+		}
+		found[name] = true
+		opt := manifest.Dictionaries[name]
+		if opt.MinWords == 0 {
+			opt.MinWords = manifest.MinWords
+		}
+		opt.hygiene = opt.hygiene.or(manifest.hygiene)
+		d, err := loadDictionary(name, filepath.Join(cfg.DictDir, e.Name()), opt, blocked)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	for name := range manifest.Dictionaries {
+		if !found[name] {
+			return nil, fmt.Errorf("%s: no word list for dictionary %q", dictManifest, name)
+		}
+	}
+	set, err := newDictSet(list, manifest.Default)
+	if err != nil {
+		return nil, err
+	}
+	set.source, set.path = sourceDirectory, cfg.DictDir
+	return set, nil
+}
+
+// loadSingleDictionary loads cfg.DictFile, or the embedded list if there is no
+// such file.
+func loadSingleDictionary() (*dictSet, error) {
+	var d *dictionary
+	var err error
+	source := sourceFile
+	if _, statErr := os.Stat(cfg.DictFile); errors.Is(statErr, fs.ErrNotExist) {
+		source = sourceEmbedded
+		d, err = readDictionary("eff-large", "embedded EFF large list", bytes.NewReader(effLargeWordlist),
+			dictOption{Label: "EFF large list", MinWords: effLargeWords}, nil)
+	} else {
+		d, err = loadDictionary("default", cfg.DictFile, dictOption{MinWords: defaultMinWords, hygiene: legacyHygiene}, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	set, err := newDictSet([]*dictionary{d}, "")
+	if err != nil {
+		return nil, err
+	}
+	set.source = source
+	if source == sourceFile {
+		set.path = cfg.DictFile
+	}
+	return set, nil
+}
+
+func loadDictionary(name, path string, opt dictOption, blocked map[string]bool) (*dictionary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readDictionary(name, path, f, opt, blocked)
+}
+
+// readDictionary reads a word list from r, which came from origin, and
+// reports what the hygiene pipeline dropped.
+func readDictionary(name, origin string, r io.Reader, opt dictOption, blocked map[string]bool) (*dictionary, error) {
+	words, dice, err := generator.ReadWords(r)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", origin, err)
+	}
+	d, err := newDictionary(name, opt, words, dice, blocked)
+	if err != nil {
+		return nil, err
+	}
+	if d.Dropped.total() > 0 {
+		log.Printf("Loaded dictionary %s: %d words from %s, dropped %d: %s", name, d.Words, origin, d.Dropped.total(), d.Dropped)
+	} else {
+		log.Printf("Loaded dictionary %s: %d words from %s", name, d.Words, origin)
+	}
+	return d, nil
+}
+
+// dictionariesHandler lists the dictionaries a request may select.
+func dictionariesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(dicts.Load().list()); err != nil {
+		http.Error(w, "encode json: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// dictStatus is the body of /api/status.
+type dictStatus struct {
+	// Source is directory, file, embedded or none.
+	Source   string    `json:"source"`
+	Path     string    `json:"path,omitempty"`
+	Degraded bool      `json:"degraded"`
+	Error    string    `json:"error,omitempty"`
+	LoadedAt time.Time `json:"loaded_at"`
+	// LastReload is the most recent failed reload, if the lists have not
+	// been reloaded successfully since.
+	LastReload   *reloadFailure `json:"last_reload_error,omitempty"`
+	Dictionaries []*dictionary  `json:"dictionaries"`
+}
+
+// statusHandler reports where the dictionaries came from and whether the
+// server is running without any.
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(currentStatus()); err != nil {
+		http.Error(w, "encode json: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// currentStatus describes the dictionaries in dicts.
+func currentStatus() dictStatus {
+	set := dicts.Load()
+	st := dictStatus{
+		Source:       set.source,
+		Path:         set.path,
+		Degraded:     set.degraded != nil,
+		LoadedAt:     set.loadedAt.UTC(),
+		LastReload:   lastReloadFailure.Load(),
+		Dictionaries: set.list(),
+	}
+	if set.degraded != nil {
+		st.Error = set.degraded.Error()
+	}
+	return st
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Ambiguous lists the characters that are easily mistaken for one another
+// in common fonts.
+const Ambiguous = "0Oo1lI|"
+
+// SymbolPresets are named symbol subsets for targets that choke on some
+// punctuation.
+var SymbolPresets = map[string]string{
+	// no quoting needed in a POSIX shell
+	"shell-safe": "%+,-./:=@_",
+	// RFC 3986 unreserved characters
+	"url-safe": "-._~",
+	// no escaping needed in XML text or attributes
+	"xml-safe": "!@#$%^*()-_=+[]{};:,.?",
+}
+
+// CharsetOptions narrows the characters every password mode draws from.
+// The policy's class minimums still apply, so a narrowing that leaves a
+// required class empty is an error. The pin and secret modes have fixed
+// alphabets and ignore these options.
+type CharsetOptions struct {
+	// ExcludeAmbiguous drops the characters in Ambiguous.
+	ExcludeAmbiguous bool
+	// Symbols is a SymbolPresets name or a literal set of symbols. The
+	// result is intersected with the policy's symbol set.
+	Symbols string
+	// Alphabet, if set, is the complete list of characters allowed; every
+	// class is reduced to its members.
+	Alphabet string
+}
+
+// IsZero reports whether no option is set.
+func (c CharsetOptions) IsZero() bool {
+	return c == CharsetOptions{}
+}
+
+// Apply returns p narrowed by the options.
+func (c CharsetOptions) Apply(p Policy) (Policy, error) {
+	if c.IsZero() {
+		return p, nil
+	}
+	forbidden := p.Forbidden
+	symbols := p.symbolSet()
+	if c.ExcludeAmbiguous {
+		forbidden += Ambiguous
+	}
+	if c.Symbols != "" {
+		set, ok := SymbolPresets[c.Symbols]
+		if !ok {
+			if err := onlySymbols("symbol set", c.Symbols); err != nil {
+				return Policy{}, err
+			}
+			set = c.Symbols
+		}
+		symbols = keep(symbols, set)
+	}
+	if c.Alphabet != "" {
+		if len(c.Alphabet) < 2 {
+			return Policy{}, fmt.Errorf("%w: alphabet needs at least two characters", ErrInvalidOption)
+		}
+		for _, r := range c.Alphabet {
+			if r <= ' ' || r > '~' {
+				return Policy{}, fmt.Errorf("%w: alphabet may only hold printable ASCII without spaces", ErrInvalidOption)
+			}
+		}
+		forbidden += without(UpperLetters+LowerLetters+Digits, c.Alphabet)
+		symbols = keep(symbols, c.Alphabet)
+	}
+	p.Forbidden = dedupe(forbidden)
+	p.Symbols = symbols
+	if p.Symbols == "" {
+		// an empty Symbols means "the default set"; forbid them instead
+		p.Forbidden = dedupe(p.Forbidden + Symbols)
+	}
+	return p, nil
+}
+
+// PresetNames returns the SymbolPresets names in order.
+func PresetNames() []string {
+	names := make([]string, 0, len(SymbolPresets))
+	for name := range SymbolPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// onlySymbols rejects a literal set holding letters, digits or spaces.
+func onlySymbols(what, set string) error {
+	for _, r := range set {
+		if r <= ' ' || r > '~' || strings.ContainsRune(UpperLetters+LowerLetters+Digits, r) {
+			return fmt.Errorf("%w: %s %q is neither a preset (%s) nor a list of ASCII symbols", ErrInvalidOption, what, set, strings.Join(PresetNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// keep returns the characters of set that also appear in allowed.
+func keep(set, allowed string) string {
+	var b strings.Builder
+	for _, r := range set {
+		if strings.ContainsRune(allowed, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// dedupe drops repeated characters, keeping the first of each.
+func dedupe(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if !strings.ContainsRune(s[:i], r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
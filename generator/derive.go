@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// DeriveParams identifies one site password for Derive, in the style of
+// LessPass and Spectre: the same inputs always give the same password.
+type DeriveParams struct {
+	// Master is the user's master passphrase.
+	Master string
+	// Site is the site name, e.g. "example.com". Case and surrounding
+	// space are ignored.
+	Site string
+	// Login is the account name on the site; it may be empty.
+	Login string
+	// Counter is bumped to rotate the password, starting at 1.
+	Counter int
+}
+
+// Argon2id cost parameters. Changing any of them changes every derived
+// password, so they are versioned together with deriveSalt.
+const (
+	deriveTime       = 3
+	deriveMemory     = 64 * 1024 // KiB
+	deriveThreads    = 4
+	deriveKeyLen     = 32
+	deriveSaltPrefix = "password-o-matic/derive/v1"
+	maxDeriveCounter = 1 << 20
+)
+
+// DeriveSeed stretches the master passphrase with Argon2id into a 32 byte
+// seed bound to the site, login and counter. Errors never echo the inputs.
+func DeriveSeed(p DeriveParams) ([]byte, error) {
+	if p.Master == "" {
+		return nil, fmt.Errorf("%w: master passphrase is empty", ErrInvalidOption)
+	}
+	site := strings.ToLower(strings.TrimSpace(p.Site))
+	if site == "" {
+		return nil, fmt.Errorf("%w: site is empty", ErrInvalidOption)
+	}
+	if p.Counter < 1 || p.Counter > maxDeriveCounter {
+		return nil, fmt.Errorf("%w: counter must be 1–%d", ErrInvalidOption, maxDeriveCounter)
+	}
+	return argon2.IDKey([]byte(p.Master), deriveSalt(site, p.Login, p.Counter), deriveTime, deriveMemory, deriveThreads, deriveKeyLen), nil
+}
+
+// deriveSalt length-prefixes each field so "ab"+"c" and "a"+"bc" differ.
+func deriveSalt(site, login string, counter int) []byte {
+	salt := []byte(deriveSaltPrefix)
+	for _, field := range []string{site, login} {
+		salt = binary.BigEndian.AppendUint32(salt, uint32(len(field)))
+		salt = append(salt, field...)
+	}
+	return binary.BigEndian.AppendUint32(salt, uint32(counter))
+}
+
+// Derive reproduces a site password: the named mode runs exactly as in
+// Generate, but every random choice comes from a RandomSource seeded with
+// DeriveSeed. The result is stable as long as the mode, its options, the
+// policy and the dictionary stay the same. Screen is not consulted: a
+// breach lookup would make the password depend on the breach data of the
+// day, and on whether the lookup succeeded.
+func (g *Generator) Derive(mode string, p DeriveParams) (Result, error) {
+	seed, err := DeriveSeed(p)
+	if err != nil {
+		return Result{}, err
+	}
+	run := *g
+	run.Rand = NewSeededSource(seed)
+	run.Screen = nil
+	return run.Generate(mode)
+}
@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDerive(t *testing.T) {
+	p := DeriveParams{Master: "correct horse battery staple", Site: "example.com", Login: "alice", Counter: 1}
+	derive := func(p DeriveParams) string {
+		res, err := New(sampleWords).Derive("random", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Password
+	}
+	want := derive(p)
+	same := p
+	same.Site = "  Example.COM "
+	if got := derive(same); got != want {
+		t.Errorf("site case and space changed the password: %q, want %q", got, want)
+	}
+	rotated := p
+	rotated.Counter = 2
+	if got := derive(rotated); got == want {
+		t.Errorf("counter 2 gave the same password %q", got)
+	}
+
+	for _, bad := range []DeriveParams{
+		{Site: "example.com", Counter: 1},
+		{Master: "m", Counter: 1},
+		{Master: "m", Site: "example.com"},
+		{Master: "m", Site: "example.com", Counter: maxDeriveCounter + 1},
+	} {
+		if _, err := New(sampleWords).Derive("random", bad); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%+v: got %v, want ErrInvalidOption", bad, err)
+		}
+	}
+}
+
+func TestDeriveIsNotScreened(t *testing.T) {
+	p := DeriveParams{Master: "correct horse battery staple", Site: "example.com", Counter: 1}
+	g := New(sampleWords)
+	want, err := g.Derive("normal", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	g.Screen = func(context.Context, string) (bool, error) {
+		calls++
+		return calls == 1, nil // reject the first draw
+	}
+	got, err := g.Derive("normal", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Password != want.Password || got.Screened != 0 || calls != 0 {
+		t.Errorf("screened derivation gave %q after %d screen calls, want %q", got.Password, calls, want.Password)
+	}
+}
@@ -0,0 +1,73 @@
+package generator
+
+import "math"
+
+// Entropy figures are the base-2 logarithm of the number of equally likely
+// outcomes of a mode's construction, assuming the attacker knows the mode,
+// the policy and the dictionary. Word choices that were rejected for being
+// too long or too short are excluded from the count, so the loss from that
+// rejection sampling is included. Shuffling is not credited.
+
+// bits returns log2(n), or 0 for n <= 1.
+func bits(n float64) float64 {
+	if n <= 1 {
+		return 0
+	}
+	return math.Log2(n)
+}
+
+// binomial returns n choose k as a float.
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	r := 1.0
+	for i := 1; i <= k; i++ {
+		r = r * float64(n-k+i) / float64(i)
+	}
+	return r
+}
+
+// lengthHistogram returns the word length histogram of g.Words, cached
+// when the Generator came from New.
+func (g *Generator) lengthHistogram() []float64 {
+	if g.stats == nil {
+		return newWordStats().histogram(g.Words)
+	}
+	return g.stats.histogram(g.Words)
+}
+
+// convolve returns the histogram of the summed lengths of a and b.
+func convolve(a, b []float64) []float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	out := make([]float64, len(a)+len(b)-1)
+	for i, x := range a {
+		if x == 0 {
+			continue
+		}
+		for j, y := range b {
+			out[i+j] += x * y
+		}
+	}
+	return out
+}
+
+// countBetween sums hist over the inclusive length range [lo, hi].
+func countBetween(hist []float64, lo, hi int) float64 {
+	total := 0.0
+	for n := max(lo, 0); n <= hi && n < len(hist); n++ {
+		total += hist[n]
+	}
+	return total
+}
+
+// forcedBits is the entropy of the characters drawn by Policy.forced.
+func (p Policy) forcedBits() float64 {
+	sets := p.sets()
+	return float64(p.Upper.Min)*bits(float64(len(sets.upper))) +
+		float64(p.Lower.Min)*bits(float64(len(sets.lower))) +
+		float64(p.Digit.Min)*bits(float64(len(sets.digit))) +
+		float64(p.Symbol.Min)*bits(float64(len(sets.symbol)))
+}
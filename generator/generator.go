@@ -0,0 +1,253 @@
+// Package generator builds the passwords served by Password-O-Matic.
+//
+// Each style of password is a Mode registered by name. A Generator holds the
+// shared state (the dictionary and the active Policy) and hands itself to
+// the selected Mode, so new modes can be added with Register without
+// touching the callers.
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ------------------------------------------------------------
+// 1. Constants
+// ------------------------------------------------------------
+// Length bounds of DefaultPolicy and the full character classes.
+const (
+	MinLength    = 20
+	MaxLength    = 27
+	Symbols      = "!@#$%^&*()-_=+[]{};:,.<>?"
+	UpperLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	LowerLetters = "abcdefghijklmnopqrstuvwxyz"
+	Digits       = "0123456789"
+)
+
+var (
+	// ErrUnknownMode is returned by Generate for a name nobody registered.
+	ErrUnknownMode = errors.New("unknown mode")
+	// ErrNoWords is returned by word-based modes when the Generator has
+	// no dictionary.
+	ErrNoWords = errors.New("no dictionary words loaded")
+	// ErrInvalidOption is returned when a mode's options are out of range.
+	ErrInvalidOption = errors.New("invalid option")
+	// ErrScreened is the last violation reported when Generator.Screen
+	// rejected every attempt.
+	ErrScreened = errors.New("rejected by screening")
+)
+
+// ------------------------------------------------------------
+// 2. Generator
+// ------------------------------------------------------------
+
+// Result is a single generated password.
+type Result struct {
+	Password string
+	// Mode is the mode that actually produced Password. It differs from
+	// the requested mode when FellBack is set.
+	Mode     string
+	FellBack bool
+	// Entropy is the theoretical strength of the construction that
+	// produced Password, in bits.
+	Entropy float64
+	// Dice holds the dice code of each word when the mode picked words
+	// from a diceware-format list, so the result can be checked by hand.
+	Dice []string
+	// Rejected counts the candidates the mode discarded before this one,
+	// e.g. weak PINs.
+	Rejected int
+	// Screened counts the candidates Generator.Screen rejected before
+	// this one.
+	Screened int
+	// Retried counts the candidates Generate discarded before this one
+	// because they failed the policy.
+	Retried int
+	// Redrawn counts the word combinations the mode drew again because
+	// they did not fit the policy's length bounds.
+	Redrawn int
+	// Raw holds the random bytes behind a machine secret, before encoding.
+	Raw []byte
+}
+
+// maxPolicyAttempts bounds how often Generate asks a mode for a new
+// password when the previous one failed the policy.
+const maxPolicyAttempts = 100
+
+// Generator carries the state shared by all modes. It is cheap to copy, so
+// callers wanting a different policy per request can copy a base Generator
+// and change the fields.
+type Generator struct {
+	// Words is the dictionary used by the word-based modes. Replace the
+	// slice rather than editing it in place; derived figures are cached
+	// per slice.
+	Words []string
+	// Dice optionally holds the diceware code of each entry in Words.
+	Dice []string
+	// Policy constrains every password. Nil means DefaultPolicy.
+	Policy *Policy
+	// Rand supplies all randomness. Nil means CryptoSource.
+	Rand RandomSource
+	// Charset narrows the characters of every password mode.
+	Charset CharsetOptions
+
+	// Passphrase configures the passphrase mode.
+	Passphrase PassphraseOptions
+	// Pronounceable configures the pronounceable mode.
+	Pronounceable PronounceableOptions
+	// PIN configures the pin mode.
+	PIN PINOptions
+	// Secret configures the machine secret modes.
+	Secret SecretOptions
+	// Pattern is the template used by the pattern mode. Empty means
+	// DefaultPattern.
+	Pattern string
+
+	// Screen, when set, sees every password that passed the policy and
+	// reports whether it must be regenerated, e.g. because it appears in
+	// breach data. It gets the context passed to GenerateContext. Modes
+	// implementing ScreenExempter are not screened.
+	Screen func(ctx context.Context, password string) (reject bool, err error)
+
+	stats *wordStats // shared by copies of the Generator
+	// allWordsForbidden is set by Generate when the policy filtered out
+	// the whole dictionary.
+	allWordsForbidden bool
+}
+
+// New returns a Generator using words as its dictionary.
+func New(words []string) *Generator {
+	return &Generator{Words: words, stats: newWordStats()}
+}
+
+// Generate produces one password using the named mode.
+func (g *Generator) Generate(mode string) (Result, error) {
+	return g.GenerateContext(context.Background(), mode)
+}
+
+// GenerateContext is Generate with a context for Screen, so that a slow
+// breach lookup stops when the caller gives up.
+func (g *Generator) GenerateContext(ctx context.Context, mode string) (Result, error) {
+	m, ok := Lookup(mode)
+	if !ok {
+		return Result{}, fmt.Errorf("%w: %q", ErrUnknownMode, mode)
+	}
+	base := g.policy()
+	if err := base.Validate(); err != nil {
+		return Result{}, err
+	}
+	p, err := g.Charset.Apply(base)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Modes see the narrowed policy and only the words it allows.
+	run := *g
+	run.Policy = &p
+	run.Charset = CharsetOptions{}
+	if g.stats != nil {
+		run.Words, run.Dice = g.stats.without(g.Words, g.Dice, p.Forbidden)
+	} else {
+		run.Words, run.Dice = newWordStats().without(g.Words, g.Dice, p.Forbidden)
+	}
+	run.allWordsForbidden = len(g.Words) > 0 && len(run.Words) == 0
+
+	if s, ok := m.(PolicyScoper); ok {
+		p = s.ScopePolicy(p)
+	} else if err := p.Validate(); err != nil {
+		// the base policy was fine, so the character set options broke it
+		return Result{}, fmt.Errorf("%w: character set options conflict with the policy: %v", ErrUnsatisfiable, err)
+	}
+	screen := g.Screen
+	if e, ok := m.(ScreenExempter); ok && e.ScreenExempt() {
+		screen = nil
+	}
+	var violation error
+	screened, retried := 0, 0
+	for attempt := 0; attempt < maxPolicyAttempts; attempt++ {
+		res, err := m.Generate(&run)
+		if err != nil {
+			return Result{}, err
+		}
+		if violation = p.Check(res.Password); violation != nil {
+			retried++
+			continue
+		}
+		if screen != nil {
+			reject, err := screen(ctx, res.Password)
+			if err != nil {
+				return Result{}, fmt.Errorf("screen password: %w", err)
+			}
+			if reject {
+				screened++
+				violation = ErrScreened
+				continue
+			}
+		}
+		if res.Mode == "" {
+			res.Mode = m.Name()
+		}
+		res.Screened = screened
+		res.Retried = retried
+		return res, nil
+	}
+	return Result{}, fmt.Errorf("%w: %s mode failed policy %q %d times, last: %v", ErrUnsatisfiable, mode, p.Name, maxPolicyAttempts, violation)
+}
+
+// policy returns the active policy.
+func (g *Generator) policy() Policy {
+	if g.Policy == nil {
+		return DefaultPolicy
+	}
+	return *g.Policy
+}
+
+// needWords reports why there is no word to pick from, if there is none.
+func (g *Generator) needWords() error {
+	if len(g.Words) > 0 {
+		return nil
+	}
+	if g.allWordsForbidden {
+		return fmt.Errorf("%w: every dictionary word contains a forbidden character", ErrUnsatisfiable)
+	}
+	return ErrNoWords
+}
+
+// randomWord returns a uniformly chosen dictionary word.
+func (g *Generator) randomWord() (string, error) {
+	if err := g.needWords(); err != nil {
+		return "", err
+	}
+	idx, err := g.randInt(len(g.Words))
+	if err != nil {
+		return "", err
+	}
+	return g.Words[idx], nil
+}
+
+// ------------------------------------------------------------
+// 3. Random helpers
+// ------------------------------------------------------------
+func (g *Generator) source() RandomSource {
+	if g.Rand == nil {
+		return CryptoSource
+	}
+	return g.Rand
+}
+
+func (g *Generator) randInt(max int) (int, error) {
+	return g.source().Intn(max)
+}
+
+func (g *Generator) randFromSet(set string) (byte, error) {
+	idx, err := g.randInt(len(set))
+	if err != nil {
+		return 0, err
+	}
+	return set[idx], nil
+}
+
+func (g *Generator) shuffle(b []byte) error {
+	return Shuffle(g.source(), len(b), func(i, j int) { b[i], b[j] = b[j], b[i] })
+}
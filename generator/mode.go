@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Mode is one way of constructing a password.
+type Mode interface {
+	// Name is the identifier used to select the mode, e.g. "normal".
+	Name() string
+	// Generate produces one password using the state held by g.
+	Generate(g *Generator) (Result, error)
+	// Describe returns a short human readable summary of the mode.
+	Describe() string
+}
+
+// PolicyScoper is implemented by modes that only honour part of a Policy.
+// Generate checks their output against ScopePolicy's result instead of the
+// full policy.
+type PolicyScoper interface {
+	ScopePolicy(p Policy) Policy
+}
+
+// ScreenExempter is implemented by modes whose output Generate does not
+// pass to Generator.Screen.
+type ScreenExempter interface {
+	ScreenExempt() bool
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Mode{}
+	order      []string
+)
+
+// Register makes a mode available by its name. It panics if the name is
+// empty or already registered, mirroring database/sql.Register.
+func Register(m Mode) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	name := m.Name()
+	if name == "" {
+		panic("generator: Register called with an empty mode name")
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("generator: Register called twice for mode %q", name))
+	}
+	registry[name] = m
+	order = append(order, name)
+}
+
+// Lookup returns the mode registered under name.
+func Lookup(name string) (Mode, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	m, ok := registry[name]
+	return m, ok
+}
+
+// Modes returns every registered mode in registration order.
+func Modes() []Mode {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make([]Mode, 0, len(order))
+	for _, name := range order {
+		out = append(out, registry[name])
+	}
+	return out
+}
+
+func init() {
+	Register(normalMode{})
+	Register(readabilityMode{})
+	Register(randomMode{})
+	Register(passphraseMode{})
+	Register(pronounceableMode{})
+	Register(pinMode{})
+	for _, m := range secretModes {
+		Register(m)
+	}
+	Register(patternMode{})
+}
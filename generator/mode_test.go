@@ -0,0 +1,306 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sampleWords is a small list of 3–9 letter words, enough for every mode.
+var sampleWords = []string{
+	"apple", "bridge", "cactus", "dolphin", "ember", "falcon", "garden", "harbor",
+	"island", "jacket", "kettle", "lantern", "meadow", "nickel", "orbit", "pepper",
+	"quartz", "river", "saddle", "timber", "umbrella", "velvet", "walnut", "yonder",
+	"zephyr", "anchor", "beacon", "candle", "desert", "engine", "forest", "glacier",
+	"hammer", "ivory", "jungle", "kitten", "ladder", "marble", "needle", "oyster",
+	"pebble", "quiver", "rocket", "silver", "tunnel", "violet", "window", "yellow",
+	"ant", "bee", "cod", "elk", "fig", "gnu", "hen", "owl",
+	"acorn", "badger", "cobalt", "drizzle", "fossil", "granite", "hollow", "juniper",
+}
+
+func TestModesUnderDefaultPolicy(t *testing.T) {
+	for _, m := range Modes() {
+		t.Run(m.Name(), func(t *testing.T) {
+			g := New(sampleWords)
+			g.Rand = NewSeededSource([]byte(m.Name()))
+			// the policy a mode's output is held to
+			p := DefaultPolicy
+			if s, ok := m.(PolicyScoper); ok {
+				p = s.ScopePolicy(p)
+			}
+			seen := map[string]bool{}
+			for i := 0; i < 50; i++ {
+				res, err := g.Generate(m.Name())
+				if err != nil {
+					t.Fatalf("Generate: %v", err)
+				}
+				if err := p.Check(res.Password); err != nil {
+					t.Errorf("%q: %v", res.Password, err)
+				}
+				if res.Mode != m.Name() && !(res.FellBack && res.Mode == "normal") {
+					t.Errorf("%q: mode %q", res.Password, res.Mode)
+				}
+				if res.Entropy <= 0 {
+					t.Errorf("%q: entropy %v", res.Password, res.Entropy)
+				}
+				seen[res.Password] = true
+			}
+			if len(seen) < 45 {
+				t.Errorf("only %d distinct passwords out of 50", len(seen))
+			}
+		})
+	}
+}
+
+func TestModesAreReproducible(t *testing.T) {
+	for _, m := range Modes() {
+		gen := func() string {
+			g := New(sampleWords)
+			g.Rand = NewSeededSource([]byte("reproducible"))
+			res, err := g.Generate(m.Name())
+			if err != nil {
+				t.Fatalf("%s: %v", m.Name(), err)
+			}
+			return res.Password
+		}
+		if a, b := gen(), gen(); a != b {
+			t.Errorf("%s: same seed gave %q and %q", m.Name(), a, b)
+		}
+	}
+}
+
+func TestModeErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		mode string
+		// setup changes the generator before Generate
+		setup func(*Generator)
+		want  error
+	}{
+		{"unknown mode", "nope", nil, ErrUnknownMode},
+		{"no words", "passphrase", func(g *Generator) { g.Words = nil }, ErrNoWords},
+		{"no words for a pattern", "pattern", func(g *Generator) { g.Words = nil }, ErrNoWords},
+		{"too few passphrase words", "passphrase", func(g *Generator) { g.Passphrase.Words = MinPassphraseWords - 1 }, ErrInvalidOption},
+		{"PIN too short", "pin", func(g *Generator) { g.PIN.Length = MinPINLength - 1 }, ErrInvalidOption},
+		{"PIN too long", "pin", func(g *Generator) { g.PIN.Length = MaxPINLength + 1 }, ErrInvalidOption},
+		{"no room for upper case", "pronounceable", func(g *Generator) {
+			g.Policy = &Policy{Name: "caps", MinLength: 8, MaxLength: 16, Upper: ClassRule{Min: 3}}
+			g.Pronounceable = PronounceableOptions{Length: 12, Digits: 6, Symbols: 4}
+		}, ErrInvalidOption},
+		{"invalid policy", "random", func(g *Generator) { g.Policy = &Policy{Name: "bad", MinLength: 5, MaxLength: 4} }, ErrInvalidPolicy},
+		{"two words in eight chars", "normal", func(g *Generator) {
+			g.Policy = &Policy{Name: "short", MinLength: 8, MaxLength: 8}
+		}, ErrUnsatisfiable},
+		{"words all forbidden", "passphrase", func(g *Generator) {
+			p := DefaultPolicy
+			p.Forbidden = "aeiou"
+			g.Policy = &p
+		}, ErrUnsatisfiable},
+		{"random source fails", "random", func(g *Generator) { g.Rand = failingSource{} }, errNoRandomness},
+		{"random source fails for a secret", "hex", func(g *Generator) { g.Rand = failingSource{} }, errNoRandomness},
+	}
+	for _, c := range cases {
+		g := New(sampleWords)
+		if c.setup != nil {
+			c.setup(g)
+		}
+		_, err := g.Generate(c.mode)
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestGenerateScreen(t *testing.T) {
+	g := New(sampleWords)
+	g.Rand = NewSeededSource([]byte("screen"))
+	calls := 0
+	g.Screen = func(_ context.Context, pwd string) (bool, error) {
+		calls++
+		return calls <= 3, nil // reject the first three candidates
+	}
+	res, err := g.Generate("random")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Screened != 3 {
+		t.Errorf("Screened = %d, want 3", res.Screened)
+	}
+
+	g.Screen = func(context.Context, string) (bool, error) { return true, nil }
+	if _, err := g.Generate("random"); !errors.Is(err, ErrUnsatisfiable) {
+		t.Errorf("rejecting everything: got %v, want ErrUnsatisfiable", err)
+	}
+
+	calls = 0
+	g.Screen = func(context.Context, string) (bool, error) { calls++; return true, nil }
+	if _, err := g.Generate("pin"); err != nil || calls != 0 {
+		t.Errorf("pin: err %v after %d screen calls, want exempt", err, calls)
+	}
+}
+
+func TestPronounceableUpperCase(t *testing.T) {
+	// more upper case than there are syllables, up to every letter
+	for _, p := range []Policy{
+		{Name: "caps", MinLength: 10, MaxLength: 10, Upper: ClassRule{Min: 8}},
+		{Name: "all caps", MinLength: 6, MaxLength: 6, Upper: ClassRule{Min: 6}},
+		{Name: "mixed", MinLength: 14, MaxLength: 16, Upper: ClassRule{Min: 9}, Lower: ClassRule{Min: 2}, Digit: ClassRule{Min: 2}},
+	} {
+		g := New(nil)
+		g.Rand = NewSeededSource([]byte(p.Name))
+		g.Policy = &p
+		for i := 0; i < 50; i++ {
+			res, err := g.Generate("pronounceable")
+			if err != nil {
+				t.Fatalf("%s: %v", p.Name, err)
+			}
+			if res.Retried > 0 {
+				t.Errorf("%s: %q took %d retries", p.Name, res.Password, res.Retried)
+			}
+		}
+	}
+}
+
+func TestIsWeakPIN(t *testing.T) {
+	cases := []struct {
+		pin  string
+		weak bool
+	}{
+		{"1234", true},   // ascending run
+		{"9876", true},   // descending run
+		{"8901", true},   // run wrapping past 9
+		{"1111", true},   // one repeated digit
+		{"1212", true},   // repeated pair
+		{"1984", true},   // year
+		{"2512", true},   // DDMM
+		{"1225", true},   // MMDD
+		{"311299", true}, // DDMMYY
+		{"19840704", true},
+		{"123456789012", true},
+		{"4822", false},
+		{"7391", false},
+		{"1332", false}, // 13 is not a month, 32 not a day
+		{"583920", false},
+		{"123", false},  // too short to be a PIN
+		{"12a4", false}, // not digits
+		{"1234567890123", false},
+	}
+	for _, c := range cases {
+		if got := IsWeakPIN(c.pin); got != c.weak {
+			t.Errorf("IsWeakPIN(%q) = %v, want %v", c.pin, got, c.weak)
+		}
+	}
+}
+
+func TestPINsAreNeverWeak(t *testing.T) {
+	for _, n := range []int{MinPINLength, DefaultPINLength, 8} {
+		g := New(nil)
+		g.Rand = NewSeededSource([]byte("pin"))
+		g.PIN.Length = n
+		for i := 0; i < 200; i++ {
+			res, err := g.Generate("pin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Password) != n || IsWeakPIN(res.Password) {
+				t.Fatalf("length %d: got %q", n, res.Password)
+			}
+		}
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		// kinds lists the token kinds, one byte per token
+		kinds string
+		err   string
+	}{
+		{"", "C=C=dddd=ss", ""}, // DefaultPattern
+		{"W", "W", ""},
+		{"u{2}d{2}-s{4}", "uudd=ssss", ""},
+		{"[a-c]{3}", "[[[", ""},
+		{`\A\1`, "==", ""},
+		{"l l", "l=l", ""},
+		{"x", "", "unknown token"},
+		{"1", "", "unknown token"},
+		{"d{0}", "", "repeat count"},
+		{"d{65}", "", "repeat count"},
+		{"d{2", "", "unclosed {"},
+		{"{2}", "", "must follow a token"},
+		{"[ab", "", "unclosed ["},
+		{"[]", "", "empty set"},
+		{"[z-a]", "", "bad range"},
+		{"d]", "", "unmatched"},
+		{`d\`, "", "trailing backslash"},
+		{"d\x01", "", "control characters"},
+		{"d{64}d{64}d{64}d{64}d", "", "more than 256 tokens"},
+		{strings.Repeat("d", 257), "", "longer than 256"},
+	}
+	for _, c := range cases {
+		tokens, err := parsePattern(c.pattern)
+		if c.err != "" {
+			if !errors.Is(err, ErrInvalidOption) || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%q: got %v, want an error about %q", c.pattern, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", c.pattern, err)
+			continue
+		}
+		var kinds []byte
+		for _, tok := range tokens {
+			kinds = append(kinds, tok.kind)
+		}
+		if string(kinds) != c.kinds {
+			t.Errorf("%q: tokens %q, want %q", c.pattern, kinds, c.kinds)
+		}
+	}
+	tokens, err := parsePattern("[a-c0]")
+	if err != nil || len(tokens) != 1 || tokens[0].set != "abc0" {
+		t.Errorf("[a-c0]: got %+v, %v", tokens, err)
+	}
+}
+
+func TestPatternOutput(t *testing.T) {
+	cases := []struct {
+		pattern string
+		policy  Policy
+		// check inspects one password
+		check func(string) bool
+		err   error
+	}{
+		{"u{2}d{2}-s{4}", DefaultPolicy, func(p string) bool {
+			return len(p) == 9 && strings.Trim(p[:2], UpperLetters) == "" && strings.Trim(p[2:4], Digits) == "" &&
+				p[4] == '-' && strings.Trim(p[5:], Symbols) == ""
+		}, nil},
+		{"[ab]{8}", DefaultPolicy, func(p string) bool { return len(p) == 8 && strings.Trim(p, "ab") == "" }, nil},
+		{"C-d{4}", DefaultPolicy, func(p string) bool { return p[0] >= 'A' && p[0] <= 'Z' && strings.Count(p, "-") == 1 }, nil},
+		{"W", DefaultPolicy, func(p string) bool { return p == strings.ToLower(p) && len(p) < 20 }, nil}, // no minimums apply
+		{"d~", DefaultPolicy, nil, ErrUnsatisfiable},                                                     // ~ is not an allowed symbol
+		{`W\Ad`, Policy{Name: "x", MinLength: 1, MaxLength: 9, Forbidden: "A"}, nil, ErrUnsatisfiable},   // forbidden literal
+		{"[0O]", Policy{Name: "x", MinLength: 1, MaxLength: 9, Forbidden: "0O"}, nil, ErrUnsatisfiable},  // every character forbidden
+	}
+	for _, c := range cases {
+		g := New(sampleWords)
+		g.Pattern = c.pattern
+		g.Policy = &c.policy
+		for i := 0; i < 20; i++ {
+			res, err := g.Generate("pattern")
+			if c.err != nil {
+				if !errors.Is(err, c.err) {
+					t.Errorf("%q: got %v, want %v", c.pattern, err, c.err)
+				}
+				break
+			}
+			if err != nil {
+				t.Fatalf("%q: %v", c.pattern, err)
+			}
+			if !c.check(res.Password) {
+				t.Errorf("%q: unexpected %q", c.pattern, res.Password)
+			}
+		}
+	}
+}
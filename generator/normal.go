@@ -0,0 +1,116 @@
+package generator
+
+import (
+	"fmt"
+)
+
+// normalMode is the original generator: two dictionary words wrapped in
+// separators followed by a shuffled pool of forced characters.
+type normalMode struct{}
+
+func (normalMode) Name() string { return "normal" }
+
+func (normalMode) Describe() string {
+	return "Two dictionary words wrapped in digits/symbols plus random characters"
+}
+
+func (normalMode) Generate(g *Generator) (Result, error) {
+	return g.normal()
+}
+
+// normal returns a normal mode password with its entropy in bits and the
+// number of word pairs drawn again.
+func (g *Generator) normal() (Result, error) {
+	policy := g.policy()
+	sets := policy.sets()
+
+	// 1. Pick two random words from the dictionary. Retry if the
+	// combined word part (including separators around each word) plus the
+	// required pool would exceed the policy's maximum length.
+	requiredPoolLen := policy.Upper.Min + policy.Lower.Min + policy.Digit.Min + policy.Symbol.Min
+	sepSet := sets.digit + sets.symbol // separators may be a digit or a symbol
+	if sepSet == "" {
+		return Result{}, fmt.Errorf("%w: normal mode needs digits or symbols for separators", ErrUnsatisfiable)
+	}
+	attempts := 0
+	var wordPart string
+	for {
+		word1, err := g.randomWord()
+		if err != nil {
+			return Result{}, err
+		}
+		word2, err := g.randomWord()
+		if err != nil {
+			return Result{}, err
+		}
+
+		// choose four separators: before/after word1, before/after word2
+		s1, err := g.randFromSet(sepSet)
+		if err != nil {
+			return Result{}, err
+		}
+		s2, err := g.randFromSet(sepSet)
+		if err != nil {
+			return Result{}, err
+		}
+		s3, err := g.randFromSet(sepSet)
+		if err != nil {
+			return Result{}, err
+		}
+		s4, err := g.randFromSet(sepSet)
+		if err != nil {
+			return Result{}, err
+		}
+
+		// assemble the word part with separators surrounding each word
+		wordPart = string([]byte{s1}) + word1 + string([]byte{s2}) + string([]byte{s3}) + word2 + string([]byte{s4})
+
+		if len(wordPart)+requiredPoolLen <= policy.MaxLength {
+			break
+		}
+		attempts++
+		if attempts >= 100 {
+			return Result{}, fmt.Errorf("%w: could not find two words that produce a password <= %d after %d attempts", ErrUnsatisfiable, policy.MaxLength, attempts)
+		}
+	}
+
+	// 2. Build a pool of required characters
+	pool, err := g.forced(policy)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// 3. Compute how many more chars we need to hit min length.
+	// `wordPart` already includes separators surrounding each word.
+	totalLen := len(wordPart) + len(pool)
+	moreNeeded := 0
+	if totalLen < policy.MinLength {
+		moreNeeded = policy.MinLength - totalLen
+	} else if totalLen > policy.MaxLength {
+		return Result{}, fmt.Errorf("word part + pool too long: %d chars, exceeds maximum %d", totalLen, policy.MaxLength)
+	}
+
+	// 4. Fill the rest with random characters from all sets
+	allSet := sets.all()
+	for i := 0; i < moreNeeded; i++ {
+		c, err := g.randFromSet(allSet)
+		if err != nil {
+			return Result{}, err
+		}
+		pool = append(pool, c)
+	}
+
+	// 5. Shuffle the pool (so the word part isn’t always at the front)
+	if err := g.shuffle(pool); err != nil {
+		return Result{}, err
+	}
+
+	// The `wordPart` already contains separators around each word; append
+	// the shuffled random pool directly (e.g. "-word1-#-word2-AB12!x").
+	// 6. Entropy: the word pairs that fit, the four separators, the forced
+	// characters and the fill.
+	pairs := countBetween(convolve(g.lengthHistogram(), g.lengthHistogram()), 0, policy.MaxLength-requiredPoolLen-4)
+	entropy := bits(pairs) + 4*bits(float64(len(sepSet))) + policy.forcedBits() + float64(moreNeeded)*bits(float64(len(allSet)))
+
+	return Result{Password: wordPart + string(pool), Entropy: entropy, Redrawn: attempts}, nil
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PassphraseOptions configures the passphrase mode. Zero values select the
+// defaults.
+type PassphraseOptions struct {
+	// Words is the number of words, 4–10. Default 6.
+	Words int
+	// Separator goes between words: "hyphen" (default), "space", "dot" or
+	// "digit" for a random digit in each gap.
+	Separator string
+	// Capitalize is "none" (default), "first" for Title Case, "upper", or
+	// "random" to title-case each word with probability one half.
+	Capitalize string
+}
+
+// Passphrase word count bounds and default.
+const (
+	MinPassphraseWords     = 4
+	MaxPassphraseWords     = 10
+	DefaultPassphraseWords = 6
+)
+
+// passphraseMode is a diceware/EFF-style passphrase: independent words
+// joined by a separator, with no overall length cap.
+type passphraseMode struct{}
+
+func (passphraseMode) Name() string { return "passphrase" }
+
+func (passphraseMode) Describe() string {
+	return "Diceware-style passphrase of 4–10 dictionary words"
+}
+
+// ScopePolicy keeps only the character rules: passphrases are longer than
+// most policies allow and deliberately skip the class minimums.
+func (passphraseMode) ScopePolicy(p Policy) Policy {
+	return Policy{
+		Name:      p.Name,
+		Symbols:   p.symbolSet() + " ",
+		Forbidden: p.Forbidden,
+		MaxRepeat: p.MaxRepeat,
+	}
+}
+
+func (passphraseMode) Generate(g *Generator) (Result, error) {
+	opts := g.Passphrase
+	n := opts.Words
+	if n == 0 {
+		n = DefaultPassphraseWords
+	}
+	if n < MinPassphraseWords || n > MaxPassphraseWords {
+		return Result{}, fmt.Errorf("%w: passphrase words must be %d–%d, got %d", ErrInvalidOption, MinPassphraseWords, MaxPassphraseWords, n)
+	}
+	sep := ""
+	switch opts.Separator {
+	case "", "hyphen":
+		sep = "-"
+	case "space":
+		sep = " "
+	case "dot":
+		sep = "."
+	case "digit":
+	default:
+		return Result{}, fmt.Errorf("%w: unknown passphrase separator %q", ErrInvalidOption, opts.Separator)
+	}
+	switch opts.Capitalize {
+	case "", "none", "first", "upper", "random":
+	default:
+		return Result{}, fmt.Errorf("%w: unknown passphrase capitalization %q", ErrInvalidOption, opts.Capitalize)
+	}
+	policy := g.policy()
+	switch {
+	case sep == "" && policy.sets().digit == "":
+		return Result{}, fmt.Errorf("%w: digit separators are forbidden by the policy", ErrUnsatisfiable)
+	case sep != "" && sep != " " && !strings.Contains(policy.sets().symbol, sep):
+		return Result{}, fmt.Errorf("%w: separator %q is not an allowed symbol", ErrUnsatisfiable, sep)
+	}
+	if err := g.needWords(); err != nil {
+		return Result{}, err
+	}
+	withDice := len(g.Dice) == len(g.Words)
+
+	var b strings.Builder
+	var dice []string
+	entropy := float64(n) * bits(float64(len(g.Words)))
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			if sep == "" {
+				d, err := g.randFromSet(policy.sets().digit)
+				if err != nil {
+					return Result{}, err
+				}
+				b.WriteByte(d)
+				entropy += bits(float64(len(policy.sets().digit)))
+			} else {
+				b.WriteString(sep)
+			}
+		}
+		idx, err := g.randInt(len(g.Words))
+		if err != nil {
+			return Result{}, err
+		}
+		word := g.Words[idx]
+		if withDice {
+			dice = append(dice, g.Dice[idx])
+		}
+		switch opts.Capitalize {
+		case "first":
+			word = titleCase(word)
+		case "upper":
+			word = strings.ToUpper(word)
+		case "random":
+			flip, err := g.randInt(2)
+			if err != nil {
+				return Result{}, err
+			}
+			if flip == 1 {
+				word = titleCase(word)
+			}
+			entropy++
+		}
+		b.WriteString(word)
+	}
+	return Result{Password: b.String(), Entropy: entropy, Dice: dice}, nil
+}
+
+// titleCase upper-cases the first letter of w.
+func titleCase(w string) string {
+	r, size := utf8.DecodeRuneInString(w)
+	if r == utf8.RuneError {
+		return w
+	}
+	return string(unicode.ToUpper(r)) + w[size:]
+}
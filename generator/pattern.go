@@ -0,0 +1,272 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pattern syntax for the pattern mode:
+//
+//	W       a dictionary word
+//	C       a capitalised dictionary word
+//	u l d s an upper case letter, lower case letter, digit or symbol
+//	[abc]   one character from the set; ranges such as [a-f0-9] work
+//	{n}     repeat the previous token n times, e.g. d{4}
+//	\x      the literal character x
+//
+// Any other punctuation or space is copied literally. Letters and digits
+// must be escaped, so a typo is reported instead of silently kept.
+//
+// "C-C-d{4}" gives "Apple-Orbit-4821"; "u{2}d{2}-s{4}" gives "QK37-#!?%".
+// Only the policy's character rules (symbols, forbidden characters and
+// repeats) apply; the template decides the length and the classes.
+
+// DefaultPattern is used when no pattern is given.
+const DefaultPattern = `C-C-d{4}-s{2}`
+
+// Pattern length limits.
+const (
+	maxPatternLen    = 256
+	maxPatternTokens = 256
+	maxPatternRepeat = 64
+)
+
+// patternToken is one position of a parsed pattern.
+type patternToken struct {
+	kind byte   // 'W', 'C', 'u', 'l', 'd', 's', '[' for a set, '=' for a literal
+	set  string // characters for '[', the literal for '='
+}
+
+// patternMode fills a user-supplied template.
+type patternMode struct{}
+
+func (patternMode) Name() string { return "pattern" }
+
+func (patternMode) Describe() string {
+	return "Fills a template such as C-C-d{4} with words and characters"
+}
+
+// ScopePolicy keeps only the character rules: the template fixes the
+// length and the classes, so a short "LLDD-SSSS" shape is not rejected for
+// missing the policy's minimums.
+func (patternMode) ScopePolicy(p Policy) Policy {
+	return Policy{
+		Name:      p.Name,
+		Symbols:   p.Symbols,
+		Forbidden: p.Forbidden,
+		MaxRepeat: p.MaxRepeat,
+	}
+}
+
+func (m patternMode) Generate(g *Generator) (Result, error) {
+	policy := m.ScopePolicy(g.policy())
+	tokens, err := parsePattern(g.Pattern)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := g.checkPattern(tokens, policy); err != nil {
+		return Result{}, err
+	}
+	sets := policy.sets()
+	var b strings.Builder
+	var entropy float64
+	for _, t := range tokens {
+		switch t.kind {
+		case 'W', 'C':
+			w, err := g.randomWord()
+			if err != nil {
+				return Result{}, err
+			}
+			if t.kind == 'C' {
+				w = titleCase(w)
+			}
+			b.WriteString(w)
+			entropy += bits(float64(len(g.Words)))
+		case '=':
+			b.WriteString(t.set)
+		default:
+			set := t.charset(sets, policy.Forbidden)
+			c, err := g.randFromSet(set)
+			if err != nil {
+				return Result{}, err
+			}
+			b.WriteByte(c)
+			entropy += bits(float64(len(set)))
+		}
+	}
+	return Result{Password: b.String(), Entropy: entropy}, nil
+}
+
+// charset returns the characters a class or set token draws from.
+func (t patternToken) charset(sets charSets, forbidden string) string {
+	switch t.kind {
+	case 'u':
+		return sets.upper
+	case 'l':
+		return sets.lower
+	case 'd':
+		return sets.digit
+	case 's':
+		return sets.symbol
+	default:
+		return without(t.set, forbidden)
+	}
+}
+
+// parsePattern parses a pattern template. Errors wrap ErrInvalidOption and
+// name the offending position.
+func parsePattern(pattern string) ([]patternToken, error) {
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	if len(pattern) > maxPatternLen {
+		return nil, fmt.Errorf("%w: pattern is longer than %d chars", ErrInvalidOption, maxPatternLen)
+	}
+	bad := func(pos int, format string, args ...any) error {
+		return fmt.Errorf("%w: pattern position %d: %s", ErrInvalidOption, pos+1, fmt.Sprintf(format, args...))
+	}
+	var tokens []patternToken
+	for i := 0; i < len(pattern); {
+		r, size := utf8.DecodeRuneInString(pattern[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			return nil, bad(i, "invalid UTF-8")
+		case strings.ContainsRune("WCulds", r):
+			tokens = append(tokens, patternToken{kind: byte(r)})
+			i++
+		case r == '\\':
+			if i+1 >= len(pattern) {
+				return nil, bad(i, "trailing backslash")
+			}
+			_, n := utf8.DecodeRuneInString(pattern[i+1:])
+			tokens = append(tokens, patternToken{kind: '=', set: pattern[i+1 : i+1+n]})
+			i += 1 + n
+		case r == '[':
+			end, set, err := parsePatternSet(pattern, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, patternToken{kind: '[', set: set})
+			i = end
+		case r == '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, bad(i, "unclosed {")
+			}
+			if len(tokens) == 0 {
+				return nil, bad(i, "{n} must follow a token")
+			}
+			n, err := strconv.Atoi(pattern[i+1 : i+end])
+			if err != nil || n < 1 || n > maxPatternRepeat {
+				return nil, bad(i, "repeat count must be 1–%d, got %q", maxPatternRepeat, pattern[i+1:i+end])
+			}
+			prev := tokens[len(tokens)-1]
+			for k := 1; k < n; k++ {
+				tokens = append(tokens, prev)
+			}
+			i += end + 1
+		case r == ']' || r == '}':
+			return nil, bad(i, "unmatched %q", r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return nil, bad(i, "unknown token %q (escape literal letters and digits with \\)", r)
+		case unicode.IsControl(r):
+			return nil, bad(i, "control characters are not allowed")
+		default:
+			tokens = append(tokens, patternToken{kind: '=', set: string(r)})
+			i += size
+		}
+		if len(tokens) > maxPatternTokens {
+			return nil, fmt.Errorf("%w: pattern expands to more than %d tokens", ErrInvalidOption, maxPatternTokens)
+		}
+	}
+	return tokens, nil
+}
+
+// parsePatternSet parses the [..] set starting at pattern[start] and
+// returns the index after the closing bracket.
+func parsePatternSet(pattern string, start int) (int, string, error) {
+	bad := func(format string, args ...any) error {
+		return fmt.Errorf("%w: pattern position %d: %s", ErrInvalidOption, start+1, fmt.Sprintf(format, args...))
+	}
+	var members []byte
+	seen := map[byte]bool{}
+	addByte := func(c byte) {
+		if !seen[c] {
+			seen[c] = true
+			members = append(members, c)
+		}
+	}
+	i := start + 1
+	for {
+		if i >= len(pattern) {
+			return 0, "", bad("unclosed [")
+		}
+		c := pattern[i]
+		if c == ']' {
+			break
+		}
+		if c >= utf8.RuneSelf || c < ' ' {
+			return 0, "", bad("sets may only hold printable ASCII")
+		}
+		if c == '\\' {
+			if i+1 >= len(pattern) {
+				return 0, "", bad("trailing backslash")
+			}
+			c = pattern[i+1]
+			i++
+		} else if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			hi := pattern[i+2]
+			if hi < c || hi >= utf8.RuneSelf {
+				return 0, "", bad("bad range %c-%c", c, hi)
+			}
+			for x := c; x <= hi; x++ {
+				addByte(x)
+			}
+			i += 3
+			continue
+		}
+		addByte(c)
+		i++
+	}
+	if len(members) == 0 {
+		return 0, "", bad("empty set []")
+	}
+	return i + 1, string(members), nil
+}
+
+// checkPattern rejects patterns that can never satisfy the policy's
+// character rules, so the caller gets a clear error instead of a timeout in
+// the retry loop. The policy is already scoped: the template decides the
+// length and the classes, so there are no minimums to check.
+func (g *Generator) checkPattern(tokens []patternToken, policy Policy) error {
+	impossible := func(format string, args ...any) error {
+		return fmt.Errorf("%w: pattern %s under policy %q", ErrUnsatisfiable, fmt.Sprintf(format, args...), policy.Name)
+	}
+	sets := policy.sets()
+	for _, t := range tokens {
+		switch t.kind {
+		case 'W', 'C':
+			if err := g.needWords(); err != nil {
+				return fmt.Errorf("pattern uses W or C: %w", err)
+			}
+		case '=':
+			r, _ := utf8.DecodeRuneInString(t.set)
+			if strings.ContainsRune(policy.Forbidden, r) {
+				return impossible("contains the forbidden literal %q", r)
+			}
+			// anything CountClasses does not call a letter or digit is a symbol
+			isSymbol := !unicode.IsUpper(r) && !unicode.IsLower(r) && !unicode.IsDigit(r)
+			if isSymbol && !strings.ContainsRune(policy.symbolSet(), r) {
+				return impossible("contains the literal %q, which is not an allowed symbol", r)
+			}
+		default:
+			if t.charset(sets, policy.Forbidden) == "" {
+				return impossible("has a position whose characters are all forbidden")
+			}
+		}
+	}
+	return nil
+}
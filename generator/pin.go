@@ -0,0 +1,172 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PINOptions configures the pin mode.
+type PINOptions struct {
+	// Length is the number of digits, 4–12. Default 6.
+	Length int
+}
+
+// PIN length bounds and default.
+const (
+	MinPINLength     = 4
+	MaxPINLength     = 12
+	DefaultPINLength = 6
+)
+
+// maxPINDraws bounds the rejection loop; with at most a few hundred
+// thousand weak PINs among 10^4..10^12 it is never reached in practice.
+const maxPINDraws = 10000
+
+// pinMode produces numeric PINs and rejects the guessable ones: runs like
+// 1234 or 9876, one repeated digit, a repeated pair like 1212, and dates or
+// years.
+type pinMode struct{}
+
+func (pinMode) Name() string { return "pin" }
+
+func (pinMode) Describe() string {
+	return "Numeric PIN of 4–12 digits without sequences, repeats or dates"
+}
+
+// ScopePolicy drops the whole policy: a PIN is digits only by definition
+// and its own weak-pattern rules replace the character rules.
+func (pinMode) ScopePolicy(p Policy) Policy {
+	return Policy{Name: p.Name}
+}
+
+// ScreenExempt skips breach screening: nearly every short digit string is
+// in a large breach corpus, and a PIN relies on the lockout of the system
+// using it rather than on being unknown.
+func (pinMode) ScreenExempt() bool { return true }
+
+func (pinMode) Generate(g *Generator) (Result, error) {
+	n := g.PIN.Length
+	if n == 0 {
+		n = DefaultPINLength
+	}
+	if n < MinPINLength || n > MaxPINLength {
+		return Result{}, fmt.Errorf("%w: pin length must be %d–%d, got %d", ErrInvalidOption, MinPINLength, MaxPINLength, n)
+	}
+	weak := weakPINs(n)
+	buf := make([]byte, n)
+	for rejected := 0; rejected < maxPINDraws; rejected++ {
+		for i := range buf {
+			d, err := g.randFromSet(Digits)
+			if err != nil {
+				return Result{}, err
+			}
+			buf[i] = d
+		}
+		if _, bad := weak[string(buf)]; bad {
+			continue
+		}
+		total := 1.0
+		for i := 0; i < n; i++ {
+			total *= 10
+		}
+		return Result{
+			Password: string(buf),
+			Entropy:  bits(total - float64(len(weak))),
+			Rejected: rejected,
+		}, nil
+	}
+	return Result{}, fmt.Errorf("%w: no acceptable pin after %d draws", ErrUnsatisfiable, maxPINDraws)
+}
+
+// IsWeakPIN reports whether pin matches one of the patterns the pin mode
+// rejects.
+func IsWeakPIN(pin string) bool {
+	if len(pin) < MinPINLength || len(pin) > MaxPINLength || strings.Trim(pin, Digits) != "" {
+		return false
+	}
+	_, bad := weakPINs(len(pin))[pin]
+	return bad
+}
+
+var (
+	weakMu    sync.Mutex
+	weakCache = map[int]map[string]struct{}{}
+)
+
+// weakPINs enumerates every rejected PIN of length n. Listing them, rather
+// than testing each candidate, gives the exact count the entropy needs.
+func weakPINs(n int) map[string]struct{} {
+	weakMu.Lock()
+	defer weakMu.Unlock()
+	if set, ok := weakCache[n]; ok {
+		return set
+	}
+	set := map[string]struct{}{}
+	add := func(s string) {
+		if len(s) == n {
+			set[s] = struct{}{}
+		}
+	}
+	buf := make([]byte, n)
+	// runs up or down, wrapping 9→0 as on a keypad (1234, 8901, 9876)
+	for start := 0; start < 10; start++ {
+		for _, step := range []int{1, 9} {
+			for i := range buf {
+				buf[i] = byte('0' + (start+i*step)%10)
+			}
+			add(string(buf))
+		}
+	}
+	// repeated pairs, which include a single repeated digit (1212, 1111)
+	for a := 0; a < 10; a++ {
+		for b := 0; b < 10; b++ {
+			for i := range buf {
+				if i%2 == 0 {
+					buf[i] = byte('0' + a)
+				} else {
+					buf[i] = byte('0' + b)
+				}
+			}
+			add(string(buf))
+		}
+	}
+	// dates and years
+	switch n {
+	case 4, 6, 8:
+		for y := 1900; y <= 2099; y++ {
+			yyyy := fmt.Sprintf("%04d", y)
+			yy := yyyy[2:]
+			add(yyyy) // 19xx / 20xx
+			for m := 1; m <= 12; m++ {
+				mm := fmt.Sprintf("%02d", m)
+				add(mm + yyyy) // MMYYYY
+				for d := 1; d <= daysIn(m); d++ {
+					dd := fmt.Sprintf("%02d", d)
+					add(mm + dd)        // MMDD
+					add(dd + mm)        // DDMM
+					add(mm + dd + yy)   // MMDDYY
+					add(dd + mm + yy)   // DDMMYY
+					add(yy + mm + dd)   // YYMMDD
+					add(mm + dd + yyyy) // MMDDYYYY
+					add(dd + mm + yyyy) // DDMMYYYY
+					add(yyyy + mm + dd) // YYYYMMDD
+				}
+			}
+		}
+	}
+	weakCache[n] = set
+	return set
+}
+
+// daysIn returns the most days month m can have, counting 29 February.
+func daysIn(m int) int {
+	switch m {
+	case 2:
+		return 29
+	case 4, 6, 9, 11:
+		return 30
+	default:
+		return 31
+	}
+}
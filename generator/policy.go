@@ -0,0 +1,377 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidPolicy is returned when a Policy contradicts itself.
+	ErrInvalidPolicy = errors.New("invalid policy")
+	// ErrPolicyViolation is returned by Policy.Check.
+	ErrPolicyViolation = errors.New("policy violation")
+	// ErrUnsatisfiable is returned when a mode cannot produce a password
+	// that satisfies the active policy.
+	ErrUnsatisfiable = errors.New("policy cannot be satisfied")
+)
+
+// ClassRule bounds how many characters of one class a password holds.
+// A Max of 0 means unlimited.
+type ClassRule struct {
+	Min int `json:"min" yaml:"min"`
+	Max int `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// Policy is a declarative set of password rules. Every mode builds its
+// passwords from the policy's character sets and lengths, and Generate
+// rejects any output that fails Check.
+type Policy struct {
+	Name      string    `json:"name" yaml:"name"`
+	MinLength int       `json:"min_length" yaml:"min_length"`
+	MaxLength int       `json:"max_length" yaml:"max_length"`
+	Upper     ClassRule `json:"upper" yaml:"upper"`
+	Lower     ClassRule `json:"lower" yaml:"lower"`
+	Digit     ClassRule `json:"digit" yaml:"digit"`
+	Symbol    ClassRule `json:"symbol" yaml:"symbol"`
+	// Symbols is the set of symbols that may appear. Empty means Symbols.
+	Symbols string `json:"symbols,omitempty" yaml:"symbols,omitempty"`
+	// Forbidden characters never appear, whatever their class.
+	Forbidden string `json:"forbidden,omitempty" yaml:"forbidden,omitempty"`
+	// MaxRepeat caps runs of one character, e.g. 2 rejects "aaa".
+	// 0 disables the check.
+	MaxRepeat int `json:"max_repeat,omitempty" yaml:"max_repeat,omitempty"`
+}
+
+// DefaultPolicy is the project's original rule set: 20–27 characters with
+// at least two of each class.
+var DefaultPolicy = Policy{
+	Name:      "default",
+	MinLength: MinLength,
+	MaxLength: MaxLength,
+	Upper:     ClassRule{Min: 2},
+	Lower:     ClassRule{Min: 2},
+	Digit:     ClassRule{Min: 2},
+	Symbol:    ClassRule{Min: 2},
+	Symbols:   Symbols,
+}
+
+// BuiltinPolicies returns DefaultPolicy followed by presets for common
+// target systems.
+func BuiltinPolicies() []Policy {
+	return []Policy{
+		DefaultPolicy,
+		{
+			// Active Directory complexity with a long minimum length.
+			Name:      "ad",
+			MinLength: 14,
+			MaxLength: 64,
+			Upper:     ClassRule{Min: 1},
+			Lower:     ClassRule{Min: 1},
+			Digit:     ClassRule{Min: 1},
+			Symbol:    ClassRule{Min: 1},
+			Symbols:   Symbols,
+		},
+		{
+			// Oracle Database: 30 bytes maximum and only the symbols that
+			// are legal in an unquoted password.
+			Name:      "oracle",
+			MinLength: 12,
+			MaxLength: 30,
+			Upper:     ClassRule{Min: 1},
+			Lower:     ClassRule{Min: 1},
+			Digit:     ClassRule{Min: 1},
+			Symbol:    ClassRule{Min: 1},
+			Symbols:   "#$_",
+		},
+		{
+			// Mainframe RACF: exactly eight upper case characters, digits
+			// and the national characters.
+			Name:      "racf",
+			MinLength: 8,
+			MaxLength: 8,
+			Upper:     ClassRule{Min: 1},
+			Digit:     ClassRule{Min: 1},
+			Symbols:   "@#$",
+			Forbidden: LowerLetters,
+			MaxRepeat: 2,
+		},
+	}
+}
+
+// Validate reports whether the policy can be satisfied at all.
+func (p Policy) Validate() error {
+	if p.MinLength < 1 || p.MaxLength < p.MinLength {
+		return fmt.Errorf("%w %q: need 1 <= min_length <= max_length, got %d..%d", ErrInvalidPolicy, p.Name, p.MinLength, p.MaxLength)
+	}
+	if p.MaxRepeat < 0 {
+		return fmt.Errorf("%w %q: max_repeat must not be negative", ErrInvalidPolicy, p.Name)
+	}
+	sets := p.sets()
+	required := 0
+	for _, c := range []struct {
+		name string
+		rule ClassRule
+		set  string
+	}{
+		{"upper", p.Upper, sets.upper},
+		{"lower", p.Lower, sets.lower},
+		{"digit", p.Digit, sets.digit},
+		{"symbol", p.Symbol, sets.symbol},
+	} {
+		if c.rule.Min < 0 || c.rule.Max < 0 {
+			return fmt.Errorf("%w %q: %s bounds must not be negative", ErrInvalidPolicy, p.Name, c.name)
+		}
+		if c.rule.Max > 0 && c.rule.Min > c.rule.Max {
+			return fmt.Errorf("%w %q: %s min %d exceeds max %d", ErrInvalidPolicy, p.Name, c.name, c.rule.Min, c.rule.Max)
+		}
+		if c.rule.Min > 0 && c.set == "" {
+			return fmt.Errorf("%w %q: %s min is %d but every %s character is forbidden", ErrInvalidPolicy, p.Name, c.name, c.rule.Min, c.name)
+		}
+		required += c.rule.Min
+	}
+	if required > p.MaxLength {
+		return fmt.Errorf("%w %q: class minimums need %d chars but max_length is %d", ErrInvalidPolicy, p.Name, required, p.MaxLength)
+	}
+	if sets.all() == "" {
+		return fmt.Errorf("%w %q: every character is forbidden", ErrInvalidPolicy, p.Name)
+	}
+	return nil
+}
+
+// Check reports the first rule pwd breaks, wrapped in ErrPolicyViolation.
+func (p Policy) Check(pwd string) error {
+	n := len([]rune(pwd))
+	if n < p.MinLength {
+		return fmt.Errorf("%w: length %d is below %d", ErrPolicyViolation, n, p.MinLength)
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		return fmt.Errorf("%w: length %d exceeds %d", ErrPolicyViolation, n, p.MaxLength)
+	}
+	symbols := p.symbolSet()
+	var counts ClassCounts
+	var prev rune
+	run := 0
+	for _, r := range pwd {
+		if strings.ContainsRune(p.Forbidden, r) {
+			return fmt.Errorf("%w: character %q is forbidden", ErrPolicyViolation, r)
+		}
+		switch {
+		case unicode.IsUpper(r):
+			counts.Upper++
+		case unicode.IsLower(r):
+			counts.Lower++
+		case unicode.IsDigit(r):
+			counts.Digit++
+		default:
+			if !strings.ContainsRune(symbols, r) {
+				return fmt.Errorf("%w: symbol %q is not allowed", ErrPolicyViolation, r)
+			}
+			counts.Symbol++
+		}
+		if r == prev {
+			run++
+		} else {
+			prev, run = r, 1
+		}
+		if p.MaxRepeat > 0 && run > p.MaxRepeat {
+			return fmt.Errorf("%w: %q repeats more than %d times in a row", ErrPolicyViolation, r, p.MaxRepeat)
+		}
+	}
+	for _, c := range []struct {
+		name string
+		rule ClassRule
+		n    int
+	}{
+		{"upper case", p.Upper, counts.Upper},
+		{"lower case", p.Lower, counts.Lower},
+		{"digit", p.Digit, counts.Digit},
+		{"symbol", p.Symbol, counts.Symbol},
+	} {
+		if c.n < c.rule.Min {
+			return fmt.Errorf("%w: %d %s chars, need at least %d", ErrPolicyViolation, c.n, c.name, c.rule.Min)
+		}
+		if c.rule.Max > 0 && c.n > c.rule.Max {
+			return fmt.Errorf("%w: %d %s chars, allowed at most %d", ErrPolicyViolation, c.n, c.name, c.rule.Max)
+		}
+	}
+	return nil
+}
+
+// ClassCounts tallies the characters of a password by class. Anything that
+// is not a letter or digit counts as a symbol.
+type ClassCounts struct {
+	Upper  int `json:"upper"`
+	Lower  int `json:"lower"`
+	Digit  int `json:"digit"`
+	Symbol int `json:"symbol"`
+}
+
+// CountClasses returns the class counts of pwd, using the same rules as
+// Policy.Check.
+func CountClasses(pwd string) ClassCounts {
+	var c ClassCounts
+	for _, r := range pwd {
+		switch {
+		case unicode.IsUpper(r):
+			c.Upper++
+		case unicode.IsLower(r):
+			c.Lower++
+		case unicode.IsDigit(r):
+			c.Digit++
+		default:
+			c.Symbol++
+		}
+	}
+	return c
+}
+
+// charSets holds the characters a policy lets each class draw from.
+type charSets struct {
+	upper, lower, digit, symbol string
+}
+
+func (s charSets) all() string { return s.upper + s.lower + s.digit + s.symbol }
+
+func (p Policy) symbolSet() string {
+	if p.Symbols == "" {
+		return Symbols
+	}
+	return p.Symbols
+}
+
+func (p Policy) sets() charSets {
+	return charSets{
+		upper:  without(UpperLetters, p.Forbidden),
+		lower:  without(LowerLetters, p.Forbidden),
+		digit:  without(Digits, p.Forbidden),
+		symbol: without(p.symbolSet(), p.Forbidden),
+	}
+}
+
+// forced draws the minimum number of characters of every class of p, in
+// class order. Callers shuffle the result.
+func (g *Generator) forced(p Policy) ([]byte, error) {
+	sets := p.sets()
+	pool := make([]byte, 0, p.Upper.Min+p.Lower.Min+p.Digit.Min+p.Symbol.Min)
+	for _, c := range []struct {
+		set string
+		n   int
+	}{
+		{sets.upper, p.Upper.Min},
+		{sets.lower, p.Lower.Min},
+		{sets.digit, p.Digit.Min},
+		{sets.symbol, p.Symbol.Min},
+	} {
+		for i := 0; i < c.n; i++ {
+			ch, err := g.randFromSet(c.set)
+			if err != nil {
+				return nil, err
+			}
+			pool = append(pool, ch)
+		}
+	}
+	return pool, nil
+}
+
+// without returns set minus every character in drop.
+func without(set, drop string) string {
+	if drop == "" {
+		return set
+	}
+	var b strings.Builder
+	for _, r := range set {
+		if !strings.ContainsRune(drop, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ------------------------------------------------------------
+// Loading policies from JSON or YAML
+// ------------------------------------------------------------
+
+// ParsePolicy decodes a policy in the given format ("json" or "yaml").
+// Fields left out default to zero, so a file only lists the rules it sets.
+// Unknown fields are rejected to catch typos.
+func ParsePolicy(data []byte, format string) (Policy, error) {
+	var p Policy
+	switch strings.ToLower(format) {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&p); err != nil {
+			return Policy{}, fmt.Errorf("decode json policy: %w", err)
+		}
+	case "yaml", "yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&p); err != nil {
+			return Policy{}, fmt.Errorf("decode yaml policy: %w", err)
+		}
+	default:
+		return Policy{}, fmt.Errorf("unsupported policy format %q", format)
+	}
+	if err := p.Validate(); err != nil {
+		return Policy{}, err
+	}
+	return p, nil
+}
+
+// LoadPolicy reads a .json, .yaml or .yml policy file. A policy without a
+// name is named after the file.
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return Policy{}, fmt.Errorf("%s: cannot tell policy format without an extension", path)
+	}
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return Policy{}, fmt.Errorf("%s: empty policy file", path)
+	}
+	p, err := ParsePolicy(data, ext[1:])
+	if err != nil {
+		return Policy{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if p.Name == "" {
+		p.Name = base
+	}
+	return p, nil
+}
+
+// LoadPolicyDir loads every policy file in dir, in name order.
+func LoadPolicyDir(dir string) ([]Policy, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []Policy
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+		if e.IsDir() {
+			continue
+		}
+		p, err := LoadPolicy(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		p    Policy
+		ok   bool
+	}{
+		{"default", DefaultPolicy, true},
+		{"lengths only", Policy{Name: "x", MinLength: 8, MaxLength: 8}, true},
+		{"zero min length", Policy{Name: "x", MinLength: 0, MaxLength: 8}, false},
+		{"max below min", Policy{Name: "x", MinLength: 10, MaxLength: 9}, false},
+		{"negative repeat", Policy{Name: "x", MinLength: 8, MaxLength: 8, MaxRepeat: -1}, false},
+		{"negative class min", Policy{Name: "x", MinLength: 8, MaxLength: 8, Digit: ClassRule{Min: -1}}, false},
+		{"class min above max", Policy{Name: "x", MinLength: 8, MaxLength: 8, Digit: ClassRule{Min: 3, Max: 2}}, false},
+		{"minimums exceed length", Policy{Name: "x", MinLength: 4, MaxLength: 4, Upper: ClassRule{Min: 3}, Digit: ClassRule{Min: 2}}, false},
+		{"required class forbidden", Policy{Name: "x", MinLength: 8, MaxLength: 8, Digit: ClassRule{Min: 1}, Forbidden: Digits}, false},
+		{"required symbol forbidden", Policy{Name: "x", MinLength: 8, MaxLength: 8, Symbol: ClassRule{Min: 1}, Symbols: "#", Forbidden: "#"}, false},
+		{"everything forbidden", Policy{Name: "x", MinLength: 8, MaxLength: 8, Symbols: "#", Forbidden: UpperLetters + LowerLetters + Digits + "#"}, false},
+	}
+	for _, c := range cases {
+		err := c.p.Validate()
+		if c.ok && err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		}
+		if !c.ok && !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("%s: got %v, want ErrInvalidPolicy", c.name, err)
+		}
+	}
+}
+
+func TestBuiltinPoliciesValidate(t *testing.T) {
+	seen := map[string]bool{}
+	for _, p := range BuiltinPolicies() {
+		if err := p.Validate(); err != nil {
+			t.Errorf("%s: %v", p.Name, err)
+		}
+		if seen[p.Name] {
+			t.Errorf("policy %q listed twice", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	if !seen[DefaultPolicy.Name] {
+		t.Errorf("BuiltinPolicies lacks %q", DefaultPolicy.Name)
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	strict := Policy{
+		Name:      "strict",
+		MinLength: 8,
+		MaxLength: 12,
+		Upper:     ClassRule{Min: 1, Max: 3},
+		Lower:     ClassRule{Min: 1},
+		Digit:     ClassRule{Min: 2},
+		Symbol:    ClassRule{Min: 1},
+		Symbols:   "#!",
+		Forbidden: "0O",
+		MaxRepeat: 2,
+	}
+	cases := []struct {
+		pwd string
+		ok  bool
+	}{
+		{"Abcdef12#", true},
+		{"ABCdef12#!", true},
+		{"Ab1#2", false},         // too short
+		{"Abcdefgh12#xy", false}, // too long
+		{"abcdefg12#", false},    // no upper case
+		{"ABCDabc12#", false},    // four upper case, at most three
+		{"ABCDEF12#", false},     // no lower case
+		{"Abcdefg1#", false},     // one digit
+		{"Abcdefg12", false},     // no symbol
+		{"Abcdef12$", false},     // $ is not an allowed symbol
+		{"Abcdef10#", false},     // 0 is forbidden
+		{"Abcccd12#", false},     // ccc repeats three times
+		{"Abccde12#", true},      // cc is fine
+		{"Äbcdef12#", true},      // upper case outside ASCII
+		{"Abcdéf12#", true},      // length counts runes
+		{"Abcdef12# ", false},    // a space counts as a symbol and is not allowed
+	}
+	for _, c := range cases {
+		err := strict.Check(c.pwd)
+		if c.ok && err != nil {
+			t.Errorf("%q: %v", c.pwd, err)
+		}
+		if !c.ok && !errors.Is(err, ErrPolicyViolation) {
+			t.Errorf("%q: got %v, want ErrPolicyViolation", c.pwd, err)
+		}
+	}
+}
+
+func TestCountClasses(t *testing.T) {
+	cases := []struct {
+		pwd  string
+		want ClassCounts
+	}{
+		{"", ClassCounts{}},
+		{"Ab1#", ClassCounts{Upper: 1, Lower: 1, Digit: 1, Symbol: 1}},
+		{"ÄÖü 9", ClassCounts{Upper: 2, Lower: 1, Digit: 1, Symbol: 1}},
+	}
+	for _, c := range cases {
+		if got := CountClasses(c.pwd); got != c.want {
+			t.Errorf("CountClasses(%q) = %+v, want %+v", c.pwd, got, c.want)
+		}
+	}
+}
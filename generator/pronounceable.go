@@ -0,0 +1,222 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PronounceableOptions configures the pronounceable mode. Zero values
+// select the defaults.
+type PronounceableOptions struct {
+	// Length is the exact password length. 0 picks a random length within
+	// the policy bounds.
+	Length int
+	// Digits and Symbols are how many digits and symbols to inject between
+	// syllables. The policy minimums are used when they ask for more.
+	Digits  int
+	Symbols int
+}
+
+// Syllable building blocks in the spirit of FIPS-181 / APG: an onset
+// (single consonant or common cluster), a vowel, and an optional coda.
+var (
+	onsets = []string{
+		"b", "c", "d", "f", "g", "h", "j", "k", "l", "m", "n", "p", "r", "s", "t", "v", "w", "z",
+		"br", "ch", "dr", "gr", "pl", "sh", "st", "th", "tr",
+	}
+	vowels = []string{"a", "e", "i", "o", "u"}
+	codas  = []string{"", "l", "m", "n", "r", "s", "t", "x"}
+)
+
+// pronounceableMode strings together consonant–vowel syllables so the
+// password can be read aloud, then injects digits and symbols at syllable
+// boundaries. It needs no dictionary.
+type pronounceableMode struct{}
+
+func (pronounceableMode) Name() string { return "pronounceable" }
+
+func (pronounceableMode) Describe() string {
+	return "Pronounceable consonant–vowel syllables with injected digits and symbols"
+}
+
+func (pronounceableMode) Generate(g *Generator) (Result, error) {
+	policy := g.policy()
+	sets := policy.sets()
+	opts := g.Pronounceable
+	var entropy float64
+
+	L := opts.Length
+	if L == 0 {
+		n, err := g.randInt(policy.MaxLength - policy.MinLength + 1)
+		if err != nil {
+			return Result{}, err
+		}
+		L = policy.MinLength + n
+		entropy += bits(float64(policy.MaxLength - policy.MinLength + 1))
+	} else if L < policy.MinLength || L > policy.MaxLength {
+		return Result{}, fmt.Errorf("%w: pronounceable length %d is outside the policy's %d–%d", ErrInvalidOption, L, policy.MinLength, policy.MaxLength)
+	}
+	if opts.Digits < 0 || opts.Symbols < 0 {
+		return Result{}, fmt.Errorf("%w: pronounceable digit and symbol counts must not be negative", ErrInvalidOption)
+	}
+	nDigits := max(opts.Digits, policy.Digit.Min)
+	nSymbols := max(opts.Symbols, policy.Symbol.Min)
+	letters := L - nDigits - nSymbols
+	if letters < 1 {
+		return Result{}, fmt.Errorf("%w: %d digits and %d symbols leave no room for syllables in %d chars", ErrInvalidOption, nDigits, nSymbols, L)
+	}
+	if policy.Upper.Min > letters {
+		return Result{}, fmt.Errorf("%w: %d digits and %d symbols leave %d letters in %d chars, but the policy needs %d upper case", ErrInvalidOption, nDigits, nSymbols, letters, L, policy.Upper.Min)
+	}
+
+	on := allowedUnits(onsets, policy.Forbidden)
+	vw := allowedUnits(vowels, policy.Forbidden)
+	co := allowedUnits(codas, policy.Forbidden)
+	if len(on) == 0 || len(vw) == 0 {
+		return Result{}, fmt.Errorf("%w: the policy forbids the letters syllables are made of", ErrUnsatisfiable)
+	}
+
+	// 1. Build syllables until exactly `letters` letters are used.
+	var syllables []string
+	for remaining := letters; remaining > 0; {
+		if remaining == 1 {
+			v, err := g.pickUnit(vw, 1, &entropy)
+			if err != nil {
+				return Result{}, err
+			}
+			syllables = append(syllables, v)
+			break
+		}
+		onset, err := g.pickUnit(on, remaining-1, &entropy)
+		if err != nil {
+			return Result{}, err
+		}
+		vowel, err := g.pickUnit(vw, 1, &entropy)
+		if err != nil {
+			return Result{}, err
+		}
+		syl := onset + vowel
+		if room := remaining - len(syl); room > 0 {
+			coda, err := g.pickUnit(co, room, &entropy)
+			if err != nil {
+				return Result{}, err
+			}
+			syl += coda
+		}
+		syllables = append(syllables, syl)
+		remaining -= len(syl)
+	}
+
+	// 2. Capitalise the start of as many syllables as the policy needs, and
+	// further letters when it needs more upper case than there are
+	// syllables.
+	if caps := policy.Upper.Min; caps > 0 && sets.upper != "" {
+		starts := min(caps, len(syllables))
+		order, err := g.shuffledIndexes(len(syllables))
+		if err != nil {
+			return Result{}, err
+		}
+		for _, idx := range order[:starts] {
+			syllables[idx] = strings.ToUpper(syllables[idx][:1]) + syllables[idx][1:]
+		}
+		entropy += bits(binomial(len(syllables), starts))
+
+		if extra := caps - starts; extra > 0 {
+			// every start is upper case now; pick among the other letters
+			type letter struct{ syl, pos int }
+			var rest []letter
+			for i, syl := range syllables {
+				for j := 1; j < len(syl); j++ {
+					rest = append(rest, letter{i, j})
+				}
+			}
+			order, err := g.shuffledIndexes(len(rest))
+			if err != nil {
+				return Result{}, err
+			}
+			for _, idx := range order[:extra] {
+				l := rest[idx]
+				syl := syllables[l.syl]
+				syllables[l.syl] = syl[:l.pos] + strings.ToUpper(syl[l.pos:l.pos+1]) + syl[l.pos+1:]
+			}
+			entropy += bits(binomial(len(rest), extra))
+		}
+	}
+
+	// 3. Inject digits and symbols at random syllable boundaries.
+	extras := make([][]byte, len(syllables)+1)
+	for _, c := range []struct {
+		set string
+		n   int
+	}{
+		{sets.digit, nDigits},
+		{sets.symbol, nSymbols},
+	} {
+		for i := 0; i < c.n; i++ {
+			ch, err := g.randFromSet(c.set)
+			if err != nil {
+				return Result{}, err
+			}
+			pos, err := g.randInt(len(extras))
+			if err != nil {
+				return Result{}, err
+			}
+			extras[pos] = append(extras[pos], ch)
+			entropy += bits(float64(len(c.set)))
+		}
+	}
+
+	var b strings.Builder
+	for i, syl := range syllables {
+		b.Write(extras[i])
+		b.WriteString(syl)
+	}
+	b.Write(extras[len(syllables)])
+	return Result{Password: b.String(), Entropy: entropy}, nil
+}
+
+// shuffledIndexes returns 0..n-1 in random order.
+func (g *Generator) shuffledIndexes(n int) ([]int, error) {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if err := Shuffle(g.source(), n, func(i, j int) { order[i], order[j] = order[j], order[i] }); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// allowedUnits drops every unit containing a forbidden character.
+func allowedUnits(units []string, forbidden string) []string {
+	if forbidden == "" {
+		return units
+	}
+	out := make([]string, 0, len(units))
+	for _, u := range units {
+		if !strings.ContainsAny(u, forbidden) {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// pickUnit picks one of the units no longer than room and adds the bits of
+// the choice to entropy.
+func (g *Generator) pickUnit(units []string, room int, entropy *float64) (string, error) {
+	fit := make([]string, 0, len(units))
+	for _, u := range units {
+		if len(u) <= room {
+			fit = append(fit, u)
+		}
+	}
+	if len(fit) == 0 {
+		return "", fmt.Errorf("%w: no syllable part fits in %d chars", ErrUnsatisfiable, room)
+	}
+	idx, err := g.randInt(len(fit))
+	if err != nil {
+		return "", err
+	}
+	*entropy += bits(float64(len(fit)))
+	return fit[idx], nil
+}
@@ -0,0 +1,41 @@
+package generator
+
+// randomMode produces fully random strings anywhere within the policy's
+// length bounds.
+type randomMode struct{}
+
+func (randomMode) Name() string { return "random" }
+
+func (randomMode) Describe() string {
+	return "Fully random characters meeting every class minimum"
+}
+
+func (randomMode) Generate(g *Generator) (Result, error) {
+	policy := g.policy()
+	sets := policy.sets()
+	lnRand, err := g.randInt(policy.MaxLength - policy.MinLength + 1)
+	if err != nil {
+		return Result{}, err
+	}
+	L := policy.MinLength + lnRand
+	// ensure categories
+	pool, err := g.forced(policy)
+	if err != nil {
+		return Result{}, err
+	}
+	allSet := sets.all()
+	for len(pool) < L {
+		c, err := g.randFromSet(allSet)
+		if err != nil {
+			return Result{}, err
+		}
+		pool = append(pool, c)
+	}
+	if err := g.shuffle(pool); err != nil {
+		return Result{}, err
+	}
+	// Entropy: the length, the forced characters and the fill.
+	fill := max(L-policy.Upper.Min-policy.Lower.Min-policy.Digit.Min-policy.Symbol.Min, 0)
+	entropy := bits(float64(policy.MaxLength-policy.MinLength+1)) + policy.forcedBits() + float64(fill)*bits(float64(len(allSet)))
+	return Result{Password: string(pool), Entropy: entropy}, nil
+}
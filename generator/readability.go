@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"strconv"
+	"unicode"
+)
+
+// readabilityMode joins three dictionary words with a few capitals, then
+// appends a four digit number and up to four symbols.
+type readabilityMode struct{}
+
+func (readabilityMode) Name() string { return "readability" }
+
+func (readabilityMode) Describe() string {
+	return "Three dictionary words with random capitals, a 4-digit number and 4 symbols"
+}
+
+func (readabilityMode) Generate(g *Generator) (Result, error) {
+	policy := g.policy()
+	sets := policy.sets()
+
+	// Four symbols, unless the policy caps or forbids them.
+	nSyms := 4
+	if policy.Symbol.Max > 0 && policy.Symbol.Max < nSyms {
+		nSyms = policy.Symbol.Max
+	}
+	if sets.symbol == "" {
+		nSyms = 0
+	}
+
+	// Pick three dictionary words whose combined length plus the fixed
+	// number (4 chars) and the symbols lands within the policy's length
+	// bounds. If we can't find such a triple after attempts, fall back to
+	// the normal generator.
+	minWordsLen := policy.MinLength - 4 - nSyms
+	maxWordsLen := policy.MaxLength - 4 - nSyms
+	if maxWordsLen < 3 {
+		return g.fallbackNormal(false, 0)
+	}
+	var sel [3]string
+	found := false
+	attempts := 0
+	for ; attempts < 1000 && !found; attempts++ {
+		for i := 0; i < 3; i++ {
+			w, err := g.randomWord()
+			if err != nil {
+				return Result{}, err
+			}
+			sel[i] = w
+		}
+		total := len(sel[0]) + len(sel[1]) + len(sel[2])
+		if total >= minWordsLen && total <= maxWordsLen {
+			found = true
+		}
+	}
+	if !found {
+		// graceful fallback to normal generator
+		return g.fallbackNormal(true, attempts)
+	}
+	combined := sel[0] + sel[1] + sel[2]
+	runes := []rune(combined)
+	// Decide how many letters to capitalize (bounded reasonably), but
+	// never fewer than the policy demands.
+	caps := 1
+	if len(runes) >= 16 {
+		caps = 2
+	}
+	if len(runes) >= 28 {
+		caps = 3
+	}
+	if caps < policy.Upper.Min {
+		caps = policy.Upper.Min
+	}
+	lowers := 0
+	for _, r := range runes {
+		if unicode.IsLower(r) {
+			lowers++
+		}
+	}
+	made := 0
+	tries := 0
+	for made < caps && tries < 200 {
+		tries++
+		idx, err := g.randInt(len(runes))
+		if err != nil {
+			return Result{}, err
+		}
+		r := runes[idx]
+		if unicode.IsLower(r) {
+			runes[idx] = unicode.ToUpper(r)
+			made++
+		}
+	}
+	numRand, err := g.randInt(9000)
+	if err != nil {
+		return Result{}, err
+	}
+	number := strconv.Itoa(numRand + 1000)
+	syms := make([]byte, 0, nSyms)
+	for i := 0; i < nSyms; i++ {
+		c, err := g.randFromSet(sets.symbol)
+		if err != nil {
+			return Result{}, err
+		}
+		syms = append(syms, c)
+	}
+	// Final check: ensure total length does not exceed the maximum (safety)
+	result := string(runes) + number + string(syms)
+	if len(result) > policy.MaxLength {
+		return g.fallbackNormal(true, attempts)
+	}
+
+	// Entropy: the word triples inside the length window, which letters
+	// were capitalised, the number and the symbols.
+	hist := g.lengthHistogram()
+	triples := countBetween(convolve(convolve(hist, hist), hist), minWordsLen, maxWordsLen)
+	entropy := bits(triples) + bits(binomial(lowers, made)) + bits(9000) + float64(nSyms)*bits(float64(len(sets.symbol)))
+	// the last attempt found the triple
+	return Result{Password: result, Entropy: entropy, Redrawn: attempts - 1}, nil
+}
+
+// fallbackNormal generates a normal password on behalf of another mode,
+// which already drew redrawn word combinations in vain.
+func (g *Generator) fallbackNormal(fellBack bool, redrawn int) (Result, error) {
+	res, err := g.normal()
+	if err != nil {
+		return Result{}, err
+	}
+	res.Redrawn += redrawn
+	if fellBack {
+		res.Mode, res.FellBack = "normal", true
+	}
+	return res, nil
+}
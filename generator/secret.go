@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// SecretOptions configures the machine secret modes (hex, base32,
+// base64url, crockford).
+type SecretOptions struct {
+	// Bytes is the number of random bytes, 8–1024. Default 32.
+	Bytes int
+	// Prefix is prepended verbatim, e.g. "pom_", so leaked-secret scanners
+	// can recognise the token. Letters, digits, '_' and '-' only.
+	Prefix string
+	// Checksum appends a CRC32 of the encoded random part, in the same
+	// encoding, so a scanner can tell a real token from a lookalike.
+	Checksum bool
+	// NoPadding drops the '=' padding from base32 output.
+	NoPadding bool
+}
+
+// Secret size bounds and default, in bytes.
+const (
+	MinSecretBytes     = 8
+	MaxSecretBytes     = 1024
+	DefaultSecretBytes = 32
+)
+
+// crockford is Douglas Crockford's base32 alphabet, which leaves out I, L,
+// O and U.
+var crockford = base32.NewEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ").WithPadding(base32.NoPadding)
+
+// secretMode encodes random bytes for API tokens and keys. Unlike the
+// password modes its output is meant for machines, so it ignores the
+// policy.
+type secretMode struct {
+	name     string
+	describe string
+	// encode turns bytes into text; pad selects padded output where the
+	// encoding has padding.
+	encode func(b []byte, pad bool) string
+}
+
+func (m secretMode) Name() string     { return m.name }
+func (m secretMode) Describe() string { return m.describe }
+
+// ScopePolicy drops the whole policy: the encoding fixes the alphabet.
+func (secretMode) ScopePolicy(p Policy) Policy {
+	return Policy{Name: p.Name}
+}
+
+func (m secretMode) Generate(g *Generator) (Result, error) {
+	opts := g.Secret
+	n := opts.Bytes
+	if n == 0 {
+		n = DefaultSecretBytes
+	}
+	if n < MinSecretBytes || n > MaxSecretBytes {
+		return Result{}, fmt.Errorf("%w: secret size must be %d–%d bytes, got %d", ErrInvalidOption, MinSecretBytes, MaxSecretBytes, n)
+	}
+	if err := validSecretPrefix(opts.Prefix); err != nil {
+		return Result{}, err
+	}
+	raw := make([]byte, n)
+	for i := range raw {
+		b, err := g.randInt(256)
+		if err != nil {
+			return Result{}, err
+		}
+		raw[i] = byte(b)
+	}
+	body := m.encode(raw, !opts.NoPadding)
+	token := opts.Prefix + body
+	if opts.Checksum {
+		token += m.checksum(body)
+	}
+	return Result{Password: token, Entropy: float64(8 * n), Raw: raw}, nil
+}
+
+// checksum is the CRC32 (IEEE) of body, big endian, in the mode's encoding
+// without padding.
+func (m secretMode) checksum(body string) string {
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE([]byte(body)))
+	return m.encode(sum[:], false)
+}
+
+// VerifySecretChecksum reports whether token, produced by the named secret
+// mode with the given prefix and Checksum set, carries a valid checksum.
+func VerifySecretChecksum(mode, prefix, token string) bool {
+	m, ok := Lookup(mode)
+	if !ok {
+		return false
+	}
+	sm, ok := m.(secretMode)
+	if !ok || !strings.HasPrefix(token, prefix) {
+		return false
+	}
+	rest := token[len(prefix):]
+	n := len(sm.checksum(""))
+	if len(rest) <= n {
+		return false
+	}
+	body, sum := rest[:len(rest)-n], rest[len(rest)-n:]
+	return sm.checksum(body) == sum
+}
+
+func validSecretPrefix(prefix string) error {
+	if len(prefix) > 32 {
+		return fmt.Errorf("%w: secret prefix is longer than 32 chars", ErrInvalidOption)
+	}
+	for _, r := range prefix {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return fmt.Errorf("%w: secret prefix may only hold letters, digits, '_' and '-'", ErrInvalidOption)
+		}
+	}
+	return nil
+}
+
+// secretModes are registered by init in mode.go.
+var secretModes = []secretMode{
+	{
+		name:     "hex",
+		describe: "Random bytes as lower case hex, for keys and tokens",
+		encode:   func(b []byte, _ bool) string { return hex.EncodeToString(b) },
+	},
+	{
+		name:     "base32",
+		describe: "Random bytes as RFC 4648 base32",
+		encode: func(b []byte, pad bool) string {
+			if pad {
+				return base32.StdEncoding.EncodeToString(b)
+			}
+			return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+		},
+	},
+	{
+		name:     "base64url",
+		describe: "Random bytes as unpadded URL-safe base64",
+		encode:   func(b []byte, _ bool) string { return base64.RawURLEncoding.EncodeToString(b) },
+	},
+	{
+		name:     "crockford",
+		describe: "Random bytes as Crockford base32",
+		encode:   func(b []byte, _ bool) string { return crockford.EncodeToString(b) },
+	},
+}
@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+)
+
+// RandomSource supplies every random choice a mode makes. Swapping it is
+// how tests get reproducible output and how deterministic derivation feeds
+// in its own bytes.
+type RandomSource interface {
+	// Intn returns a uniformly distributed integer in [0, n). n must be
+	// positive.
+	Intn(n int) (int, error)
+}
+
+// CryptoSource draws from crypto/rand. It is the default for a Generator
+// without a Rand and is safe for concurrent use.
+var CryptoSource RandomSource = NewReaderSource(rand.Reader)
+
+// NewReaderSource returns a RandomSource that turns the bytes read from r
+// into uniform integers by rejection sampling, so there is no modulo bias.
+// It is safe for concurrent use only if r is.
+func NewReaderSource(r io.Reader) RandomSource {
+	return readerSource{r: r}
+}
+
+// NewSeededSource returns a deterministic RandomSource: the same seed always
+// yields the same sequence. The seed is hashed into a ChaCha8 key, so any
+// length works. It is meant for tests and derivation, is not safe for
+// concurrent use, and must never be used with a guessable seed to make real
+// passwords.
+func NewSeededSource(seed []byte) RandomSource {
+	return readerSource{r: mathrand.NewChaCha8(sha256.Sum256(seed))}
+}
+
+type readerSource struct {
+	r io.Reader
+}
+
+func (s readerSource) Intn(n int) (int, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("random source: Intn called with n = %d", n)
+	}
+	if n == 1 {
+		return 0, nil
+	}
+	// Values below 2^64 mod n would make the low results more likely, so
+	// they are drawn again.
+	bound := uint64(n)
+	threshold := -bound % bound
+	var buf [8]byte
+	for {
+		if _, err := io.ReadFull(s.r, buf[:]); err != nil {
+			return 0, fmt.Errorf("random source: %w", err)
+		}
+		v := uint64(buf[0]) | uint64(buf[1])<<8 | uint64(buf[2])<<16 | uint64(buf[3])<<24 |
+			uint64(buf[4])<<32 | uint64(buf[5])<<40 | uint64(buf[6])<<48 | uint64(buf[7])<<56
+		if v >= threshold {
+			return int(v % bound), nil
+		}
+	}
+}
+
+// Shuffle permutes n elements with a Fisher–Yates shuffle driven by src.
+// swap exchanges the elements with indexes i and j.
+func Shuffle(src RandomSource, n int, swap func(i, j int)) error {
+	for i := n - 1; i > 0; i-- {
+		j, err := src.Intn(i + 1)
+		if err != nil {
+			return err
+		}
+		swap(i, j)
+	}
+	return nil
+}
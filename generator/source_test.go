@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSeededSourceIsDeterministic(t *testing.T) {
+	draw := func(seed string) []int {
+		src := NewSeededSource([]byte(seed))
+		out := make([]int, 50)
+		for i := range out {
+			n, err := src.Intn(1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out[i] = n
+		}
+		return out
+	}
+	a, b, c := draw("seed"), draw("seed"), draw("other seed")
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed gave different draws:\n%v\n%v", a, b)
+	}
+	if reflect.DeepEqual(a, c) {
+		t.Errorf("different seeds gave the same draws: %v", a)
+	}
+}
+
+func TestSourceIntn(t *testing.T) {
+	sources := []struct {
+		name string
+		src  RandomSource
+	}{
+		{"crypto", CryptoSource},
+		{"seeded", NewSeededSource([]byte("intn"))},
+	}
+	for _, s := range sources {
+		for _, n := range []int{1, 2, 3, 10, 1000, 1 << 40} {
+			for i := 0; i < 200; i++ {
+				v, err := s.src.Intn(n)
+				if err != nil {
+					t.Fatalf("%s: Intn(%d): %v", s.name, n, err)
+				}
+				if v < 0 || v >= n {
+					t.Fatalf("%s: Intn(%d) = %d, out of range", s.name, n, v)
+				}
+			}
+		}
+		for _, n := range []int{0, -1} {
+			if _, err := s.src.Intn(n); err == nil {
+				t.Errorf("%s: Intn(%d) succeeded, want an error", s.name, n)
+			}
+		}
+	}
+}
+
+func TestSourceIntnCoversRange(t *testing.T) {
+	src := NewSeededSource([]byte("coverage"))
+	seen := make([]int, 6)
+	for i := 0; i < 6000; i++ {
+		v, err := src.Intn(len(seen))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[v]++
+	}
+	// each face expects 1000; a fair source is far inside these bounds
+	for v, n := range seen {
+		if n < 800 || n > 1200 {
+			t.Errorf("value %d drawn %d times out of 6000: %v", v, n, seen)
+		}
+	}
+}
+
+func TestReaderSourceReportsReadErrors(t *testing.T) {
+	src := NewReaderSource(bytes.NewReader([]byte{1, 2, 3})) // short of 8 bytes
+	if _, err := src.Intn(10); err == nil {
+		t.Fatal("Intn on an exhausted reader succeeded")
+	}
+	if err := Shuffle(src, 5, func(i, j int) {}); err == nil {
+		t.Fatal("Shuffle on an exhausted reader succeeded")
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 10, 100} {
+		in := make([]int, n)
+		for i := range in {
+			in[i] = i
+		}
+		shuffle := func(seed string) []int {
+			s := append([]int(nil), in...)
+			err := Shuffle(NewSeededSource([]byte(seed)), len(s), func(i, j int) { s[i], s[j] = s[j], s[i] })
+			if err != nil {
+				t.Fatalf("n=%d: %v", n, err)
+			}
+			return s
+		}
+		a, b := shuffle("shuffle"), shuffle("shuffle")
+		if !slices.Equal(a, b) {
+			t.Errorf("n=%d: same seed gave different orders:\n%v\n%v", n, a, b)
+		}
+		if sorted := slices.Sorted(slices.Values(a)); !slices.Equal(sorted, in) {
+			t.Errorf("n=%d: %v is not a permutation", n, a)
+		}
+		if n >= 10 && slices.Equal(a, in) {
+			t.Errorf("n=%d: order unchanged", n)
+		}
+	}
+}
+
+func TestShuffleIsUniform(t *testing.T) {
+	// all 6 orders of three elements should come up about equally often
+	src := NewSeededSource([]byte("uniform"))
+	counts := map[[3]byte]int{}
+	for i := 0; i < 6000; i++ {
+		s := [3]byte{'a', 'b', 'c'}
+		if err := Shuffle(src, 3, func(i, j int) { s[i], s[j] = s[j], s[i] }); err != nil {
+			t.Fatal(err)
+		}
+		counts[s]++
+	}
+	if len(counts) != 6 {
+		t.Fatalf("got %d distinct orders, want 6: %v", len(counts), counts)
+	}
+	for order, n := range counts {
+		if n < 800 || n > 1200 {
+			t.Errorf("order %s came up %d times out of 6000", order[:], n)
+		}
+	}
+}
+
+func TestGeneratorUsesRand(t *testing.T) {
+	gen := func() string {
+		g := New(sampleWords)
+		g.Rand = NewSeededSource([]byte("generator"))
+		res, err := g.Generate("normal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Password
+	}
+	if a, b := gen(), gen(); a != b {
+		t.Errorf("seeded generators disagree: %q and %q", a, b)
+	}
+}
+
+var errNoRandomness = errors.New("no randomness")
+
+// failingSource always fails, to check that modes pass errors on.
+type failingSource struct{}
+
+func (failingSource) Intn(int) (int, error) { return 0, errNoRandomness }
@@ -0,0 +1,160 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ReadWords reads a word list with one word per line. Blank lines are
+// skipped and surrounding whitespace is trimmed.
+//
+// Diceware-format lists, where each line is a dice code and a word
+// ("11111\tabacus"), are recognised as well: the codes are stripped from the
+// words and returned in dice, parallel to words. dice is nil unless every
+// line carries a code.
+func ReadWords(r io.Reader) (words, dice []string, err error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" { // skip empty lines
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read words: %w", err)
+	}
+
+	words = make([]string, 0, len(lines))
+	dice = make([]string, 0, len(lines))
+	for _, line := range lines {
+		code, word, ok := splitDiceLine(line)
+		if !ok {
+			// not a diceware list: every line is a word as written
+			return lines, nil, nil
+		}
+		dice = append(dice, code)
+		words = append(words, word)
+	}
+	if len(words) == 0 {
+		return nil, nil, nil
+	}
+	return words, dice, nil
+}
+
+// splitDiceLine splits "11111\tword" into its dice code and word.
+func splitDiceLine(line string) (code, word string, ok bool) {
+	code, word, found := strings.Cut(line, "\t")
+	if !found {
+		code, word, found = strings.Cut(line, " ")
+	}
+	if !found || len(code) < 2 {
+		return "", "", false
+	}
+	for _, r := range code {
+		if r < '1' || r > '6' {
+			return "", "", false
+		}
+	}
+	word = strings.TrimSpace(word)
+	if word == "" || strings.ContainsAny(word, " \t") {
+		return "", "", false
+	}
+	return code, word, true
+}
+
+// wordStats caches figures that are costly to recompute for every password:
+// the word length histogram of a dictionary and the dictionary filtered by a
+// set of forbidden characters. Entries are keyed by slice identity, so a
+// replaced dictionary simply misses.
+type wordStats struct {
+	mu       sync.Mutex
+	hist     map[sliceKey][]float64
+	filtered map[filterKey]wordSubset
+}
+
+// maxCachedFilters bounds the filtered lists kept; callers can send any
+// forbidden set, so the cache starts over rather than grow without limit.
+const maxCachedFilters = 32
+
+type sliceKey struct {
+	first *string
+	n     int
+}
+
+type filterKey struct {
+	words     sliceKey
+	forbidden string
+}
+
+type wordSubset struct {
+	words, dice []string
+}
+
+func newWordStats() *wordStats {
+	return &wordStats{hist: map[sliceKey][]float64{}, filtered: map[filterKey]wordSubset{}}
+}
+
+func keyOf(words []string) sliceKey {
+	if len(words) == 0 {
+		return sliceKey{}
+	}
+	return sliceKey{first: &words[0], n: len(words)}
+}
+
+// histogram returns counts[n] = number of words of n bytes. The byte
+// length is used because that is what the modes measure.
+func (s *wordStats) histogram(words []string) []float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := keyOf(words)
+	if counts, ok := s.hist[key]; ok {
+		return counts
+	}
+	var counts []float64
+	for _, w := range words {
+		for len(counts) <= len(w) {
+			counts = append(counts, 0)
+		}
+		counts[len(w)]++
+	}
+	if len(s.hist) >= maxCachedFilters {
+		clear(s.hist)
+	}
+	s.hist[key] = counts
+	return counts
+}
+
+// without returns the words (and their dice codes, if dice is parallel to
+// words) that contain none of the forbidden characters.
+func (s *wordStats) without(words, dice []string, forbidden string) ([]string, []string) {
+	if forbidden == "" {
+		return words, dice
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := filterKey{words: keyOf(words), forbidden: forbidden}
+	if sub, ok := s.filtered[key]; ok {
+		return sub.words, sub.dice
+	}
+	withDice := len(dice) == len(words)
+	var sub wordSubset
+	for i, w := range words {
+		if strings.ContainsAny(w, forbidden) {
+			continue
+		}
+		sub.words = append(sub.words, w)
+		if withDice {
+			sub.dice = append(sub.dice, dice[i])
+		}
+	}
+	if len(s.filtered) >= maxCachedFilters {
+		clear(s.filtered)
+	}
+	s.filtered[key] = sub
+	return sub.words, sub.dice
+}
@@ -0,0 +1,12 @@
+module github.com/Yoshiofthewire/Password-O-Matic
+
+go 1.23.0
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/crypto v0.40.0
+	golang.org/x/text v0.27.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.34.0 // indirect
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
+)
+
+// ------------------------------------------------------------
+// Health, readiness and build info
+// ------------------------------------------------------------
+
+// certExpiryMargin is how long before it expires the served certificate
+// makes the server unready, so that a failed renewal shows up in time.
+const certExpiryMargin = 7 * 24 * time.Hour
+
+// servedCert is the certificate the server presents. runServer sets it.
+var servedCert atomic.Pointer[x509.Certificate]
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3".
+// Without it the module version the Go toolchain stamped into the binary
+// is reported.
+var version string
+
+// healthzHandler answers as long as the process can serve requests at
+// all; it checks nothing else.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	io.WriteString(w, "ok\n")
+}
+
+// readinessCheck is the outcome of one readiness condition.
+type readinessCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// readiness is the body of /readyz.
+type readiness struct {
+	Ready  bool             `json:"ready"`
+	Checks []readinessCheck `json:"checks"`
+}
+
+// readyzHandler answers 200 when the server can do its job and 503, with
+// the failed checks, when it cannot.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	res := checkReadiness(time.Now())
+	status := http.StatusOK
+	if !res.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
+
+func checkReadiness(now time.Time) readiness {
+	res := readiness{Ready: true, Checks: []readinessCheck{
+		dictionaryReadiness(dicts.Load()),
+		certReadiness(servedCert.Load(), now),
+		entropyReadiness(),
+	}}
+	for _, c := range res.Checks {
+		res.Ready = res.Ready && c.OK
+	}
+	return res
+}
+
+// dictionaryReadiness looks at the dictionaries as last loaded, at startup
+// or by a reload, rather than reading the files again.
+func dictionaryReadiness(set *dictSet) readinessCheck {
+	c := readinessCheck{Name: "dictionary"}
+	switch {
+	case set == nil:
+		c.Detail = "not loaded yet"
+	case set.degraded != nil:
+		c.Detail = "no dictionary loaded: " + set.degraded.Error()
+	default:
+		d, _ := set.lookup("")
+		c.OK = true
+		c.Detail = fmt.Sprintf("%d loaded, default %s with %d words", len(set.names), d.Name, d.Words)
+	}
+	return c
+}
+
+func certReadiness(cert *x509.Certificate, now time.Time) readinessCheck {
+	c := readinessCheck{Name: "certificate"}
+	switch {
+	case cert == nil:
+		c.Detail = "no certificate loaded"
+	case now.Before(cert.NotBefore):
+		c.Detail = "not valid until " + cert.NotBefore.UTC().Format(time.RFC3339)
+	case !now.Before(cert.NotAfter):
+		c.Detail = "expired at " + cert.NotAfter.UTC().Format(time.RFC3339)
+	case cert.NotAfter.Sub(now) < certExpiryMargin:
+		c.Detail = fmt.Sprintf("expires at %s, within %d days", cert.NotAfter.UTC().Format(time.RFC3339), int(certExpiryMargin.Hours()/24))
+	default:
+		c.OK = true
+		c.Detail = "valid until " + cert.NotAfter.UTC().Format(time.RFC3339)
+	}
+	return c
+}
+
+// entropyReadiness draws from the source every generator uses by default.
+func entropyReadiness() readinessCheck {
+	c := readinessCheck{Name: "entropy"}
+	if _, err := generator.CryptoSource.Intn(1 << 30); err != nil {
+		c.Detail = err.Error()
+		return c
+	}
+	c.OK = true
+	c.Detail = "crypto/rand readable"
+	return c
+}
+
+// buildInfo is the body of /version.
+type buildInfo struct {
+	Version string `json:"version"`
+	// Commit, CommitTime and Modified describe the checkout the binary
+	// was built from, when the build recorded it.
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commit_time,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	GoVersion  string `json:"go_version"`
+	// Dictionary is the default dictionary, unless none is loaded.
+	Dictionary *dictionarySize `json:"dictionary,omitempty"`
+}
+
+type dictionarySize struct {
+	Name  string `json:"name"`
+	Words int    `json:"words"`
+}
+
+// readBuildInfo collects what the Go toolchain recorded in the binary.
+var readBuildInfo = sync.OnceValue(func() buildInfo {
+	info := buildInfo{Version: version, GoVersion: runtime.Version()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == "" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.time":
+			info.CommitTime = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+})
+
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	info := readBuildInfo()
+	if set := dicts.Load(); set != nil && set.degraded == nil {
+		d, _ := set.lookup("")
+		info.Dictionary = &dictionarySize{Name: d.Name, Words: d.Words}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		http.Error(w, "encode json: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
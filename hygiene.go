@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ------------------------------------------------------------
+// Word list hygiene
+// ------------------------------------------------------------
+
+// hygiene is the clean-up every word list goes through before it is
+// validated. Zero fields take their value from the enclosing level of the
+// manifest, and finally from defaultHygiene.
+type hygiene struct {
+	// MinLength and MaxLength bound words in characters. Long words make
+	// the length-limited modes retry until the policy fits.
+	MinLength int `yaml:"min_length"`
+	MaxLength int `yaml:"max_length"`
+	// AllowedChars, when set, drops words with any other character.
+	AllowedChars string `yaml:"allowed_chars"`
+	// KeepCase turns off lower-casing.
+	KeepCase *bool `yaml:"keep_case"`
+}
+
+var defaultHygiene = hygiene{MinLength: 3, MaxLength: 10}
+
+// legacyHygiene cleans the single dict_file, which was used as is before
+// lists were cleaned: no length bounds and no lower-casing, so an existing
+// dictionary.txt keeps all its words. Only repeats are dropped.
+var legacyHygiene = hygiene{MinLength: 1, MaxLength: math.MaxInt, KeepCase: &keepCase}
+
+var keepCase = true
+
+// or fills the unset fields of h from def.
+func (h hygiene) or(def hygiene) hygiene {
+	if h.MinLength == 0 {
+		h.MinLength = def.MinLength
+	}
+	if h.MaxLength == 0 {
+		h.MaxLength = def.MaxLength
+	}
+	if h.AllowedChars == "" {
+		h.AllowedChars = def.AllowedChars
+	}
+	if h.KeepCase == nil {
+		h.KeepCase = def.KeepCase
+	}
+	return h
+}
+
+func (h hygiene) validate() error {
+	if h.MinLength < 1 || h.MaxLength < h.MinLength {
+		return fmt.Errorf("word lengths must satisfy 1 <= min_length <= max_length, got %d and %d", h.MinLength, h.MaxLength)
+	}
+	if !utf8.ValidString(h.AllowedChars) {
+		return errors.New("allowed_chars is not valid UTF-8")
+	}
+	return nil
+}
+
+// dropReport counts the words clean removed, by reason.
+type dropReport struct {
+	TooShort   int `json:"too_short"`
+	TooLong    int `json:"too_long"`
+	Disallowed int `json:"disallowed_chars"`
+	Blocked    int `json:"blocked"`
+	Duplicates int `json:"duplicates"`
+}
+
+func (r dropReport) total() int {
+	return r.TooShort + r.TooLong + r.Disallowed + r.Blocked + r.Duplicates
+}
+
+func (r dropReport) String() string {
+	return fmt.Sprintf("%d too short, %d too long, %d with characters not allowed, %d blocked, %d duplicates",
+		r.TooShort, r.TooLong, r.Disallowed, r.Blocked, r.Duplicates)
+}
+
+// clean normalises words to NFC and, unless KeepCase is set, lower case,
+// then drops those outside the length bounds, with characters not in
+// AllowedChars, on the blocklist, or seen before. dice, if parallel to
+// words, is filtered along with them.
+func (h hygiene) clean(words, dice []string, blocked map[string]bool) ([]string, []string, dropReport) {
+	var r dropReport
+	withDice := len(dice) == len(words)
+	keepCase := h.KeepCase != nil && *h.KeepCase
+	allowed := h.AllowedChars
+	if !keepCase {
+		allowed = strings.ToLower(allowed)
+	}
+	seen := make(map[string]bool, len(words))
+	outWords := make([]string, 0, len(words))
+	var outDice []string
+	for i, w := range words {
+		w = norm.NFC.String(w)
+		if !keepCase {
+			w = strings.ToLower(w)
+		}
+		n := utf8.RuneCountInString(w)
+		switch {
+		case n < h.MinLength:
+			r.TooShort++
+			continue
+		case n > h.MaxLength:
+			r.TooLong++
+			continue
+		case allowed != "" && strings.IndexFunc(w, func(c rune) bool { return !strings.ContainsRune(allowed, c) }) >= 0:
+			r.Disallowed++
+			continue
+		case blocked[strings.ToLower(w)]:
+			r.Blocked++
+			continue
+		case seen[w]:
+			r.Duplicates++
+			continue
+		}
+		seen[w] = true
+		outWords = append(outWords, w)
+		if withDice {
+			outDice = append(outDice, dice[i])
+		}
+	}
+	return outWords, outDice, r
+}
+
+// checkEncoding rejects a list that is not valid UTF-8, which usually
+// means a file in another encoding; dropping its words one by one would
+// quietly remove every accented word.
+func checkEncoding(words []string) error {
+	for i, w := range words {
+		if !utf8.ValidString(w) {
+			return fmt.Errorf("word %d is not valid UTF-8", i+1)
+		}
+	}
+	return nil
+}
+
+// loadBlocklist reads path, one word per line with # comments, into the
+// normalised lower-case set clean compares against. A missing file is an
+// empty blocklist.
+func loadBlocklist(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	blocked := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocked[strings.ToLower(norm.NFC.String(line))] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return blocked, nil
+}
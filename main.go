@@ -1,7 +1,7 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -9,330 +9,86 @@ import (
 	"crypto/x509/pkix"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"html"
+	"io/fs"
 	"log"
+	"math"
 	"math/big"
-	mathrand "math/rand"
 	"net/http"
 	"os"
-	"strconv"
+	"os/signal"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
-	"unicode"
-)
 
-// ------------------------------------------------------------
-// 1. Constants & global data
-// ------------------------------------------------------------
-const (
-	port         = ":8443"
-	dictFile     = "dictionary.txt"
-	certFile     = "cert.pem"
-	keyFile      = "key.pem"
-	minPwdLen    = 20
-	maxPwdLen    = 27
-	symbols      = "!@#$%^&*()-_=+[]{};:,.<>?"
-	upperLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	lowerLetters = "abcdefghijklmnopqrstuvwxyz"
-	digits       = "0123456789"
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
 )
 
 // ------------------------------------------------------------
-// 2. Load the dictionary once at startup
+// 1. Constants & global data
 // ------------------------------------------------------------
-var wordList []string
-
-func loadDictionary() error {
-	f, err := os.Open(dictFile)
-	if err != nil {
-		return fmt.Errorf("open %s: %w", dictFile, err)
-	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		word := strings.TrimSpace(scanner.Text())
-		if word == "" { // skip empty lines
-			continue
-		}
-		wordList = append(wordList, word)
-	}
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("scan %s: %w", dictFile, err)
-	}
 
-	if len(wordList) < 10000 {
-		return fmt.Errorf("%s contains only %d words – at least 10 000 required", dictFile, len(wordList))
-	}
-	return nil
-}
+// cfg holds the settings. setup loads them from the defaults, the config
+// file, the environment and flags; see config.go.
+var cfg = defaultConfig()
 
 // ------------------------------------------------------------
-// 3. Random helpers
+// 2. Password generator
 // ------------------------------------------------------------
-func randInt(max int64) (int64, error) {
-	nBig, err := rand.Int(rand.Reader, big.NewInt(max))
-	if err != nil {
-		return 0, err
-	}
-	return nBig.Int64(), nil
-}
 
-func randFromSet(set string) (byte, error) {
-	idx, err := randInt(int64(len(set)))
-	if err != nil {
-		return 0, err
-	}
-	return set[int(idx)], nil
-}
-
-// ------------------------------------------------------------
-// 4. Password generator
-// ------------------------------------------------------------
-func generatePassword() (string, error) {
-	// 1. Pick two random words from the dictionary. Retry if the
-	// combined word part (including separators around each word) plus the
-	// required pool would exceed maxPwdLen.
-	const requiredPoolLen = 2 + 2 + 4 + 2 // number of forced chars added below
-	sepSet := digits + symbols            // separators may be a digit or a symbol
-	attempts := 0
-	var wordPart string
-	for {
-		wIdx1, err := randInt(int64(len(wordList)))
-		if err != nil {
-			return "", err
-		}
-		wIdx2, err := randInt(int64(len(wordList)))
-		if err != nil {
-			return "", err
-		}
+// Word lists live in dicts (see dicts.go), each with a generator built from
+// it, and are reloaded when their files change. Modes come from the
+// generator registry, so every registered mode is served.
 
-		// choose four separators: before/after word1, before/after word2
-		s1, err := randFromSet(sepSet)
-		if err != nil {
-			return "", err
-		}
-		s2, err := randFromSet(sepSet)
-		if err != nil {
-			return "", err
-		}
-		s3, err := randFromSet(sepSet)
-		if err != nil {
-			return "", err
-		}
-		s4, err := randFromSet(sepSet)
-		if err != nil {
-			return "", err
-		}
-
-		// assemble the word part with separators surrounding each word
-		word1 := wordList[int(wIdx1)]
-		word2 := wordList[int(wIdx2)]
-		wordPart = string([]byte{s1}) + word1 + string([]byte{s2}) + string([]byte{s3}) + word2 + string([]byte{s4})
-
-		if len(wordPart)+requiredPoolLen <= maxPwdLen {
-			break
-		}
-		attempts++
-		if attempts >= 100 {
-			return "", fmt.Errorf("could not find two words that produce a password <= %d after %d attempts", maxPwdLen, attempts)
-		}
-		// small pause to mix entropy source a bit (not strictly necessary)
-		time.Sleep(5 * time.Millisecond)
-	}
+// policies holds the built-in policies plus any found in cfg.PolicyDir, keyed
+// by name. Requests pick one with ?policy=<name>. The "default" entry is
+// cfg.defaultPolicy(), not the generator package's, so the configured
+// length bounds and symbols apply.
+var policies = map[string]generator.Policy{}
 
-	// 2. Build a pool of required characters
-	pool := []byte{}
-	for i := 0; i < 2; i++ {
-		c, err := randFromSet(upperLetters)
-		if err != nil {
-			return "", err
-		}
-		pool = append(pool, c)
+func loadPolicies() error {
+	for _, p := range generator.BuiltinPolicies() {
+		policies[p.Name] = p
 	}
-	for i := 0; i < 2; i++ {
-		c, err := randFromSet(lowerLetters)
-		if err != nil {
-			return "", err
-		}
-		pool = append(pool, c)
+	def := cfg.defaultPolicy()
+	policies[def.Name] = def
+	loaded, err := generator.LoadPolicyDir(cfg.PolicyDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil // the directory is optional
 	}
-	for i := 0; i < 4; i++ {
-		c, err := randFromSet(digits)
-		if err != nil {
-			return "", err
-		}
-		pool = append(pool, c)
+	if err != nil {
+		return err
 	}
-	for i := 0; i < 2; i++ {
-		c, err := randFromSet(symbols)
-		if err != nil {
-			return "", err
-		}
-		pool = append(pool, c)
+	for _, p := range loaded {
+		policies[p.Name] = p
 	}
+	return nil
+}
 
-	// 3. Compute how many more chars we need to hit min length.
-	// `wordPart` already includes separators surrounding each word.
-	totalLen := len(wordPart) + len(pool)
-	moreNeeded := 0
-	if totalLen < minPwdLen {
-		moreNeeded = minPwdLen - totalLen
-	} else if totalLen > maxPwdLen {
-		return "", fmt.Errorf("word part + pool too long: %d chars, exceeds maximum %d", totalLen, maxPwdLen)
+// generatorFor returns a copy of the named dictionary's generator using
+// the named policy. Empty names select the defaults.
+func generatorFor(dictName, policyName string) (*generator.Generator, error) {
+	base, ok := dicts.Load().generator(dictName)
+	if !ok {
+		return nil, badParam("dict", "unknown dictionary %q", dictName)
 	}
-
-	// 4. Fill the rest with random characters from all sets
-	allSet := upperLetters + lowerLetters + digits + symbols
-	for i := 0; i < moreNeeded; i++ {
-		c, err := randFromSet(allSet)
-		if err != nil {
-			return "", err
-		}
-		pool = append(pool, c)
+	if policyName == "" {
+		policyName = generator.DefaultPolicy.Name
 	}
-
-	// 5. Shuffle the pool (so the word part isn’t always at the front)
-	mathrand.Shuffle(len(pool), func(i, j int) {
-		pool[i], pool[j] = pool[j], pool[i]
-	})
-
-	// The `wordPart` already contains separators around each word; append
-	// the shuffled random pool directly (e.g. "-word1-#-word2-AB12!x").
-	return wordPart + string(pool), nil
-}
-
-// generatePasswordMode produces a password according to the requested mode.
-// Supported modes: "normal" (original generator), "readability", "random".
-// generatePasswordMode produces a password according to the requested mode.
-// It returns (password, fellBackToNormal, error).
-func generatePasswordMode(mode string) (string, bool, error) {
-	switch mode {
-	case "normal":
-		p, err := generatePassword()
-		return p, false, err
-	case "readability":
-		// Readability mode: pick three dictionary words whose combined
-		// length plus the fixed number (4 chars) and 4 symbols fits within
-		// maxPwdLen. If we can't find such a triple after attempts, fall
-		// back to the normal generator.
-		maxWordsLen := maxPwdLen - 4 - 4 // 4-digit number + 4 symbols
-		if maxWordsLen < 3 {
-			p, err := generatePassword()
-			return p, false, err
-		}
-		var sel [3]string
-		found := false
-		for attempts := 0; attempts < 1000 && !found; attempts++ {
-			for i := 0; i < 3; i++ {
-				idx, err := randInt(int64(len(wordList)))
-				if err != nil {
-					return "", false, err
-				}
-				sel[i] = wordList[int(idx)]
-			}
-			total := len(sel[0]) + len(sel[1]) + len(sel[2])
-			if total <= maxWordsLen {
-				found = true
-			}
-		}
-		if !found {
-			// graceful fallback to normal generator
-			p, err := generatePassword()
-			return p, true, err
-		}
-		combined := sel[0] + sel[1] + sel[2]
-		runes := []rune(combined)
-		// Decide how many letters to capitalize (bounded reasonably).
-		caps := 1
-		if len(runes) >= 16 {
-			caps = 2
-		}
-		if len(runes) >= 28 {
-			caps = 3
-		}
-		made := 0
-		tries := 0
-		for made < caps && tries < 200 {
-			tries++
-			idx, err := randInt(int64(len(runes)))
-			if err != nil {
-				return "", false, err
-			}
-			r := runes[int(idx)]
-			if unicode.IsLetter(r) {
-				runes[int(idx)] = unicode.ToUpper(r)
-				made++
-			}
-		}
-		numRand, err := randInt(9000)
-		if err != nil {
-			return "", false, err
-		}
-		number := strconv.Itoa(int(numRand) + 1000)
-		syms := make([]byte, 0, 4)
-		for i := 0; i < 4; i++ {
-			c, err := randFromSet(symbols)
-			if err != nil {
-				return "", false, err
-			}
-			syms = append(syms, c)
-		}
-		// Final check: ensure total length does not exceed maxPwdLen (safety)
-		result := string(runes) + number + string(syms)
-		if len(result) > maxPwdLen {
-			p, err := generatePassword()
-			return p, true, err
-		}
-		return result, false, nil
-	case "random":
-		// length 20..27
-		lnRand, err := randInt(8)
-		if err != nil {
-			return "", false, err
-		}
-		L := 20 + int(lnRand)
-		pool := make([]byte, 0, L)
-		// ensure categories
-		for i := 0; i < 2; i++ {
-			c, err := randFromSet(upperLetters + lowerLetters)
-			if err != nil {
-				return "", false, err
-			}
-			pool = append(pool, c)
-		}
-		for i := 0; i < 2; i++ {
-			c, err := randFromSet(digits)
-			if err != nil {
-				return "", false, err
-			}
-			pool = append(pool, c)
-		}
-		for i := 0; i < 2; i++ {
-			c, err := randFromSet(symbols)
-			if err != nil {
-				return "", false, err
-			}
-			pool = append(pool, c)
-		}
-		allSet := upperLetters + lowerLetters + digits + symbols
-		for len(pool) < L {
-			c, err := randFromSet(allSet)
-			if err != nil {
-				return "", false, err
-			}
-			pool = append(pool, c)
-		}
-		mathrand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
-		return string(pool), false, nil
-	default:
-		p, err := generatePassword()
-		return p, false, err
+	p, ok := policies[policyName]
+	if !ok {
+		return nil, badParam("policy", "unknown policy %q", policyName)
 	}
+	g := *base
+	g.Policy = &p
+	return &g, nil
 }
 
 // ------------------------------------------------------------
-// 5. HTTP handler
+// 3. HTTP handler
 // ------------------------------------------------------------
 func pwdHandler(w http.ResponseWriter, r *http.Request) {
 	// Render an empty grid of twelve password placeholders. The client
@@ -343,6 +99,32 @@ func pwdHandler(w http.ResponseWriter, r *http.Request) {
 		pwHTML.WriteString("<div class=\"pwd\" onclick=\"copyPwd(this)\"></div>\n")
 	}
 
+	// One menu entry per registered mode; "normal" starts out active.
+	var menuHTML strings.Builder
+	for _, m := range generator.Modes() {
+		name := html.EscapeString(m.Name())
+		class := ""
+		if m.Name() == "normal" {
+			class = ` class="active"`
+		}
+		label := strings.ToUpper(name[:1]) + name[1:]
+		fmt.Fprintf(&menuHTML, "<button data-mode=\"%s\" title=\"%s\"%s>%s</button>\n", name, html.EscapeString(m.Describe()), class, label)
+	}
+
+	// A dictionary picker, shown only when there is a choice.
+	var dictHTML strings.Builder
+	if list := dicts.Load().list(); len(list) > 1 {
+		dictHTML.WriteString(`<select id="dictSel" class="dictSel" aria-label="Dictionary" title="Dictionary">` + "\n")
+		for _, d := range list {
+			selected := ""
+			if d.Default {
+				selected = " selected"
+			}
+			fmt.Fprintf(&dictHTML, "<option value=\"%s\"%s>%s</option>\n", html.EscapeString(d.Name), selected, html.EscapeString(d.Label))
+		}
+		dictHTML.WriteString("</select>\n")
+	}
+
 	var page strings.Builder
 	page.WriteString(`<!doctype html>
 	<html lang="en">
@@ -375,6 +157,7 @@ h1{text-align:left;color:#f3e8ff;margin:0;font-size:1.35rem}
 .controls{display:flex;align-items:center;gap:.5rem}
 .regen{width:44px;height:44px;display:inline-flex;align-items:center;justify-content:center;background:linear-gradient(90deg,#ff2d95,#c4007a);color:white;border:none;padding:0;border-radius:.35rem;font-weight:700;cursor:pointer;box-shadow:0 6px 18px rgba(196,0,122,0.12);margin-right:6px}
 .regen:hover{filter:brightness(.95)}
+.dictSel{height:44px;padding:0 .6rem;background:#121015;color:#e6e1ee;border:1px solid rgba(255,45,149,0.25);border-radius:.35rem;font:inherit;cursor:pointer}
 .regen svg{width:20px;height:20px;display:block}
 
 /* hamburger menu button */
@@ -387,6 +170,7 @@ h1{text-align:left;color:#f3e8ff;margin:0;font-size:1.35rem}
 .menuPopup button.active{background:linear-gradient(90deg,#ff2d95,#c4007a);color:white}
 
 .pwd{background:#1b1220;padding:1.2rem 1rem;border-radius:.4rem;font-weight:600;text-align:center;white-space:normal;overflow-wrap:anywhere;word-break:break-word;font-size:1.08rem;min-height:3.6rem;cursor:pointer;user-select:text;color:#ffffff;border:1px solid rgba(255,45,149,0.12);min-width:0}
+.pwd .bits{display:block;margin-top:.35rem;font-size:.72rem;font-weight:400;color:#bfb7d6}
 .pwd:hover{background:rgba(255,45,149,0.06);box-shadow:0 6px 18px rgba(77,0,102,0.12)}
 
 .note{text-align:center;margin-top:.75rem;color:#bfb7d6;font-size:.95rem}
@@ -425,7 +209,12 @@ h1{text-align:left;color:#f3e8ff;margin:0;font-size:1.35rem}
 		<div class="header">
 			<h1>Password-O-Matic</h1>
 						<div class="controls" style="position:relative">
-								<button id="regen" class="regen" type="button" aria-label="Generate new passwords" title="New set">
+`)
+
+	// insert the dictionary picker, if any
+	page.WriteString(dictHTML.String())
+
+	page.WriteString(`								<button id="regen" class="regen" type="button" aria-label="Generate new passwords" title="New set">
 												<!-- refresh icon (rounded square) -->
 												<svg viewBox="0 0 24 24" fill="none" xmlns="http://www.w3.org/2000/svg" aria-hidden="true" focusable="false">
 													<path d="M21 12a9 9 0 10-2.64 6.12" stroke="currentColor" stroke-width="1.6" stroke-linecap="round" stroke-linejoin="round"/>
@@ -440,10 +229,12 @@ h1{text-align:left;color:#f3e8ff;margin:0;font-size:1.35rem}
 												</svg>
 										</button>
 										<div id="menuPopup" class="menuPopup" role="menu" aria-hidden="true">
-												<button data-mode="readability">Readability</button>
-												<button data-mode="normal" class="active">Normal</button>
-												<button data-mode="random">Random</button>
-										</div>
+`)
+
+	// insert one button per registered mode
+	page.WriteString(menuHTML.String())
+
+	page.WriteString(`										</div>
 								</div>
 						</div>
 				</div>
@@ -473,7 +264,7 @@ h1{text-align:left;color:#f3e8ff;margin:0;font-size:1.35rem}
 		}
 
 		function copyPwd(el){
-			const txt = el.innerText;
+			const txt = el.dataset.pwd || '';
 			navigator.clipboard.writeText(txt).then(()=>{
 				showToast('Copied');
 			}).catch(()=>showToast('Copy failed'));
@@ -481,6 +272,7 @@ h1{text-align:left;color:#f3e8ff;margin:0;font-size:1.35rem}
 
 		// Fetch new passwords via AJAX and animate swap in-place.
 				let currentMode = 'normal';
+				let currentDict = '';
 				function setCookie(name, value, days){
 					const d = new Date();
 					d.setTime(d.getTime() + (days*24*60*60*1000));
@@ -500,10 +292,13 @@ h1{text-align:left;color:#f3e8ff;margin:0;font-size:1.35rem}
 			if(b) b.disabled = true;
 			try{
 				if(b) b.classList.add('spin');
-				const res = await fetch('/api/passwords?mode='+encodeURIComponent(currentMode));
+				let url = '/api/passwords?mode='+encodeURIComponent(currentMode);
+				if(currentDict) url += '&dict='+encodeURIComponent(currentDict);
+				const res = await fetch(url);
 				if(!res.ok) throw new Error('status '+res.status);
 				const body = await res.json();
 				const pwds = Array.isArray(body) ? body : (body.pwds || []);
+				const bits = (body && body.entropy) || [];
 				const fellBack = body && body.fallback;
 				const cells = Array.from(document.querySelectorAll('.grid .pwd'));
 				// fade out all cells in parallel
@@ -513,7 +308,14 @@ h1{text-align:left;color:#f3e8ff;margin:0;font-size:1.35rem}
 				// replace content and fade in
 				for(let i=0;i<cells.length && i<pwds.length;i++){
 					const el = cells[i];
+					el.dataset.pwd = pwds[i];
 					el.textContent = pwds[i];
+					if(typeof bits[i] === 'number'){
+						const small = document.createElement('span');
+						small.className = 'bits';
+						small.textContent = '≈ ' + Math.round(bits[i]) + ' bits of entropy';
+						el.appendChild(small);
+					}
 					// remove any stale classes
 					el.classList.remove('fade-out');
 					el.classList.add('fade-in');
@@ -544,6 +346,20 @@ h1{text-align:left;color:#f3e8ff;margin:0;font-size:1.35rem}
 			if(saved){
 				currentMode = saved;
 			}
+			// restore the saved dictionary, if it is still offered
+			const dictSel = document.getElementById('dictSel');
+			if(dictSel){
+				const savedDict = getCookie('pwd_dict');
+				if(Array.from(dictSel.options).some(o=>o.value===savedDict)){
+					dictSel.value = savedDict;
+				}
+				currentDict = dictSel.value;
+				dictSel.addEventListener('change', ()=>{
+					currentDict = dictSel.value;
+					setCookie('pwd_dict', currentDict, 365);
+					regenPasswords();
+				});
+			}
 			if(popup){
 				// set active button according to currentMode
 				const activeBtn = popup.querySelector('button[data-mode="'+currentMode+'"]');
@@ -588,27 +404,65 @@ h1{text-align:left;color:#f3e8ff;margin:0;font-size:1.35rem}
 	fmt.Fprint(w, page.String())
 }
 
-// apiHandler returns a JSON array of N generated passwords.
+// apiHandler returns a JSON array of generated passwords. Settings come
+// from the query string or a form body; see genParams.
 func apiHandler(w http.ResponseWriter, r *http.Request) {
-	const n = 12
-	pwds := make([]string, 0, n)
-	mode := r.URL.Query().Get("mode")
-	if mode == "" {
+	if err := r.ParseForm(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	params, err := parseParams(r.Form)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	n, err := params.count()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	mode := params.Mode
+	if _, ok := generator.Lookup(mode); !ok {
+		// unknown or missing modes have always been served as "normal"
 		mode = "normal"
 	}
+	g, err := params.generator()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	pwds := make([]string, 0, n)
+	entropy := make([]float64, 0, n)
+	var dice [][]string
+	rejected, screened := 0, 0
 	anyFallback := false
 	for i := 0; i < n; i++ {
-		p, fellBack, err := generatePasswordMode(mode)
+		res, err := generate(r.Context(), g, mode)
 		if err != nil {
-			http.Error(w, "Could not generate password: "+err.Error(), http.StatusInternalServerError)
+			writeAPIError(w, generateErrorStatus(err), fmt.Errorf("could not generate password: %w", err))
 			return
 		}
-		if fellBack {
+		if res.FellBack {
 			anyFallback = true
 		}
-		pwds = append(pwds, p)
+		pwds = append(pwds, res.Password)
+		entropy = append(entropy, roundBits(res.Entropy))
+		if res.Dice != nil {
+			dice = append(dice, res.Dice)
+		}
+		rejected += res.Rejected
+		screened += res.Screened
+	}
+	resp := map[string]interface{}{"pwds": pwds, "entropy": entropy, "fallback": anyFallback}
+	if dice != nil {
+		resp["dice"] = dice
+	}
+	if rejected > 0 {
+		resp["rejected"] = rejected
+	}
+	if screened > 0 {
+		resp["screened"] = screened
 	}
-	resp := map[string]interface{}{"pwds": pwds, "fallback": anyFallback}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		http.Error(w, "encode json: "+err.Error(), http.StatusInternalServerError)
@@ -616,15 +470,66 @@ func apiHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// roundBits rounds an entropy figure to one decimal place for display.
+func roundBits(b float64) float64 {
+	return math.Round(b*10) / 10
+}
+
+// generateErrorStatus maps a generator error to an HTTP status: errors
+// caused by the request's choices are the client's fault.
+func generateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, generator.ErrInvalidPolicy), errors.Is(err, generator.ErrUnsatisfiable),
+		errors.Is(err, generator.ErrInvalidOption):
+		return http.StatusBadRequest
+	case errors.Is(err, generator.ErrNoWords):
+		// degraded: no dictionary could be loaded
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// policiesHandler lists the policies a request may select.
+func policiesHandler(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]generator.Policy, 0, len(names))
+	for _, name := range names {
+		list = append(list, policies[name])
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		http.Error(w, "encode json: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
 func generateSelfSignedCert() error {
 	// If both cert and key exist, nothing to do.
-	if _, err := os.Stat(certFile); err == nil {
-		if _, err2 := os.Stat(keyFile); err2 == nil {
-			return nil
+	if certExists() {
+		return nil
+	}
+	log.Println("Generating new self-signed cert…")
+	return writeSelfSignedCert()
+}
+
+// certExists reports whether both the certificate and the key are present.
+func certExists() bool {
+	if _, err := os.Stat(cfg.CertFile); err == nil {
+		if _, err2 := os.Stat(cfg.KeyFile); err2 == nil {
+			return true
 		}
 	}
+	return false
+}
 
-	log.Println("Generating new self-signed cert…")
+// writeSelfSignedCert writes a new key and a certificate for localhost,
+// valid for a year, to cfg.KeyFile and cfg.CertFile.
+func writeSelfSignedCert() error {
 
 	// Generate a private key.
 	priv, err := rsa.GenerateKey(rand.Reader, 2048)
@@ -675,10 +580,10 @@ func generateSelfSignedCert() error {
 	}
 
 	// Write files with appropriate permissions.
-	if err := os.WriteFile(certFile, certOut, 0644); err != nil {
+	if err := os.WriteFile(cfg.CertFile, certOut, 0644); err != nil {
 		return fmt.Errorf("write cert file: %w", err)
 	}
-	if err := os.WriteFile(keyFile, keyOut, 0600); err != nil {
+	if err := os.WriteFile(cfg.KeyFile, keyOut, 0600); err != nil {
 		return fmt.Errorf("write key file: %w", err)
 	}
 
@@ -686,50 +591,124 @@ func generateSelfSignedCert() error {
 }
 
 // ------------------------------------------------------------
-// 7. Main
+// 4. Main
 // ------------------------------------------------------------
 func main() {
+	os.Exit(runCLI(os.Args[1:]))
+}
 
-	// If run with `--sample`, print a number of generated passwords to stdout
-	// and exit. This is a debug mode to verify lengths without starting the server.
-	if len(os.Args) > 1 && os.Args[1] == "--sample" {
-		if err := loadDictionary(); err != nil {
-			log.Fatalf("Failed to load dictionary: %v", err)
-		}
-		for i := 0; i < 200; i++ {
-			pwd, err := generatePassword()
-			if err != nil {
-				fmt.Printf("error: %v\n", err)
-				continue
-			}
-			fmt.Printf("%d %s\n", len(pwd), pwd)
-		}
-		return
+// setup loads what every command needs: the configuration, the policies
+// and the breach data. Dictionaries are left to the commands that use them.
+func setup(flags *configFlags) error {
+	if err := configure(flags); err != nil {
+		return err
 	}
-
-	if err := loadDictionary(); err != nil {
-		log.Fatalf("Failed to load dictionary: %v", err)
+	if err := loadPolicies(); err != nil {
+		return fmt.Errorf("load policies: %w", err)
+	}
+	if err := loadBreachData(); err != nil {
+		return fmt.Errorf("load breach data: %w", err)
 	}
+	return nil
+}
+
+// runServer runs the web server until it fails or is stopped by SIGTERM
+// or SIGINT.
+func runServer() error {
+	dicts.Store(startupDictionaries())
+	watchDictionaries()
 
 	if err := generateSelfSignedCert(); err != nil {
-		log.Fatalf("Could not create TLS cert: %v", err)
+		return fmt.Errorf("create TLS cert: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS cert: %w", err)
 	}
+	servedCert.Store(cert.Leaf)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", pwdHandler)
 	// API endpoint for fetching a fresh set of passwords via AJAX
 	mux.HandleFunc("/api/passwords", apiHandler)
+	mux.HandleFunc("/api/policies", policiesHandler)
+	mux.HandleFunc("/api/dictionaries", dictionariesHandler)
+	// Where the dictionaries came from, and whether any loaded
+	mux.HandleFunc("/api/status", statusHandler)
+	// Deterministic site passwords; POST only, inputs are never logged
+	mux.HandleFunc("/api/derive", deriveHandler)
+	// Strength estimate for a user-chosen password; POST only, never logged
+	mux.HandleFunc("/api/check", checkHandler)
+	// Typed JSON API with per-password metadata and problem+json errors
+	mux.HandleFunc("/api/v2/generate", apiV2GenerateHandler)
+	// Bulk generation, written as NDJSON or CSV rows while generating
+	mux.HandleFunc("/api/stream", streamHandler)
+	// OpenAPI document and a browser explorer for it
+	mux.HandleFunc("/api/openapi.json", openAPIHandler)
+	mux.HandleFunc("/api/docs", docsHandler)
+	// Liveness and readiness probes, and what build is running
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	mux.HandleFunc("/version", versionHandler)
+	// Prometheus metrics for generation and HTTP traffic
+	mux.HandleFunc("/metrics", metricsHandler)
 
 	srv := &http.Server{
-		Addr:    port,
-		Handler: mux,
+		Addr:              cfg.Listen,
+		Handler:           instrument(mux),
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
 		TLSConfig: &tls.Config{
-			MinVersion: tls.VersionTLS12,
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
 		},
 	}
+	stopped := make(chan struct{})
+	go shutdownOnSignal(srv, stopped)
+
+	log.Printf("Serving on https://%s (copy button will work with HTTPS)", displayAddr(cfg.Listen))
+	if err := srv.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	// ListenAndServeTLS returns as soon as shutdown starts
+	<-stopped
+	return nil
+}
+
+// shutdownOnSignal waits for SIGTERM, as sent by Kubernetes before it
+// removes a pod, or SIGINT. It then stops accepting connections and lets
+// the requests in flight finish for up to cfg.ShutdownTimeout before
+// closing what is left; a second signal closes them at once. stopped is
+// closed when the server is down.
+func shutdownOnSignal(srv *http.Server, stopped chan<- struct{}) {
+	defer close(stopped)
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+	log.Printf("Received %v, finishing requests in flight", <-sig)
+
+	ctx := context.Background()
+	if d := time.Duration(cfg.ShutdownTimeout); d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	go func() {
+		log.Printf("Received %v again, closing connections now", <-sig)
+		srv.Close()
+	}()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Requests still running after %s, closing their connections", cfg.ShutdownTimeout)
+		srv.Close()
+	}
+	log.Print("Server stopped")
+}
 
-	log.Printf("Serving on https://localhost%s (copy button will work with HTTPS)", port)
-	if err := srv.ListenAndServeTLS(certFile, keyFile); err != nil {
-		log.Fatalf("Server failed: %v", err)
+// displayAddr is addr as a browser would reach it from this machine.
+func displayAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
 	}
+	return addr
 }
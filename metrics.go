@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
+)
+
+// ------------------------------------------------------------
+// Metrics
+// ------------------------------------------------------------
+// /metrics is written in the Prometheus text exposition format. The few
+// metric types needed are implemented here rather than pulling in the
+// Prometheus client library.
+
+var (
+	passwordsGenerated = newCounter("pwomatic_passwords_generated_total",
+		"Passwords generated, by requested mode.", "mode")
+	generationFallbacks = newCounter("pwomatic_generation_fallbacks_total",
+		"Passwords a mode handed over to the normal mode, e.g. when readability found no word triple within the policy.", "mode")
+	generationRetries = newCounter("pwomatic_generation_retries_total",
+		"Candidates discarded and generated again, by mode and reason (words, rejected, policy or screened).", "mode", "reason")
+	generationErrors = newCounter("pwomatic_generation_errors_total",
+		"Generation attempts that failed, by mode.", "mode")
+	generationDuration = newHistogram("pwomatic_generation_duration_seconds",
+		"Time taken to generate one password, by mode.",
+		[]float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5}, "mode")
+
+	httpRequests = newCounter("pwomatic_http_requests_total",
+		"HTTP requests answered, by route and status code.", "route", "code")
+	httpDuration = newHistogram("pwomatic_http_request_duration_seconds",
+		"Time taken to answer an HTTP request, by route.",
+		[]float64{.001, .005, .01, .05, .1, .5, 1, 5, 30, 120}, "route")
+
+	dictionaryWords = newGaugeFunc("pwomatic_dictionary_words",
+		"Words in each loaded dictionary.", []string{"dictionary"},
+		func(emit func(v float64, labels ...string)) {
+			set := dicts.Load()
+			if set == nil {
+				return
+			}
+			for _, d := range set.list() {
+				emit(float64(d.Words), d.Name)
+			}
+		})
+)
+
+// metricFamilies is every metric in the order /metrics writes them.
+var metricFamilies = []interface{ write(io.Writer) }{
+	passwordsGenerated, generationFallbacks, generationRetries, generationErrors, generationDuration,
+	httpRequests, httpDuration,
+	dictionaryWords,
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	for _, m := range metricFamilies {
+		m.write(w)
+	}
+}
+
+// generate is g.GenerateContext, recorded in the generation metrics.
+func generate(ctx context.Context, g *generator.Generator, mode string) (generator.Result, error) {
+	start := time.Now()
+	res, err := g.GenerateContext(ctx, mode)
+	elapsed := time.Since(start)
+
+	if _, ok := generator.Lookup(mode); !ok {
+		mode = "unknown" // keep what a client typed out of the labels
+	}
+	generationDuration.observe(elapsed.Seconds(), mode)
+	if err != nil {
+		generationErrors.add(1, mode)
+		return res, err
+	}
+	passwordsGenerated.add(1, mode)
+	if res.FellBack {
+		generationFallbacks.add(1, mode)
+	}
+	if res.Redrawn > 0 {
+		generationRetries.add(float64(res.Redrawn), mode, "words")
+	}
+	if res.Rejected > 0 {
+		generationRetries.add(float64(res.Rejected), mode, "rejected")
+	}
+	if res.Retried > 0 {
+		generationRetries.add(float64(res.Retried), mode, "policy")
+	}
+	if res.Screened > 0 {
+		generationRetries.add(float64(res.Screened), mode, "screened")
+	}
+	return res, nil
+}
+
+// instrument records every request h answers in the HTTP metrics. The
+// route is the ServeMux pattern that matched, so arbitrary paths do not
+// each get their own series.
+func instrument(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r)
+		route := r.Pattern
+		if route == "" {
+			route = "other"
+		}
+		code := sw.code
+		if code == 0 {
+			code = http.StatusOK
+		}
+		httpRequests.add(1, route, strconv.Itoa(code))
+		httpDuration.observe(time.Since(start).Seconds(), route)
+	})
+}
+
+// statusWriter remembers the status code of a response. Unwrap lets
+// http.ResponseController reach the flusher and deadlines underneath.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 && code >= 200 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ------------------------------------------------------------
+// Exposition format
+// ------------------------------------------------------------
+
+// counter is a counter family. Series are keyed by their rendered label
+// set, which also orders them in the output.
+type counter struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	series map[string]float64
+}
+
+func newCounter(name, help string, labels ...string) *counter {
+	return &counter{name: name, help: help, labels: labels, series: map[string]float64{}}
+}
+
+// add increases the series with the given label values by v.
+func (c *counter) add(v float64, values ...string) {
+	key := labelSet(c.labels, values)
+	c.mu.Lock()
+	c.series[key] += v
+	c.mu.Unlock()
+}
+
+func (c *counter) write(w io.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.series) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, key, formatValue(c.series[key]))
+	}
+}
+
+// histogram is a histogram family with fixed upper bucket bounds.
+type histogram struct {
+	name, help string
+	labels     []string
+	bounds     []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(name, help string, bounds []float64, labels ...string) *histogram {
+	return &histogram{name: name, help: help, labels: labels, bounds: bounds, series: map[string]*histogramSeries{}}
+}
+
+// observe records v in the series with the given label values.
+func (h *histogram) observe(v float64, values ...string) {
+	key := labelSet(h.labels, values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &histogramSeries{values: values, counts: make([]uint64, len(h.bounds))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.bounds, v); i < len(h.bounds) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *histogram) write(w io.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	labels := append(h.labels[:len(h.labels):len(h.labels)], "le")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		values := append(s.values[:len(s.values):len(s.values)], "")
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += s.counts[i]
+			values[len(values)-1] = formatValue(bound)
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelSet(labels, values), cumulative)
+		}
+		values[len(values)-1] = "+Inf"
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelSet(labels, values), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, key, formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, key, s.count)
+	}
+}
+
+// gaugeFunc is a gauge family whose series are read when /metrics is
+// scraped, for figures the server already keeps elsewhere.
+type gaugeFunc struct {
+	name, help string
+	labels     []string
+	collect    func(emit func(v float64, values ...string))
+}
+
+func newGaugeFunc(name, help string, labels []string, collect func(emit func(v float64, values ...string))) *gaugeFunc {
+	return &gaugeFunc{name: name, help: help, labels: labels, collect: collect}
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	g.collect(func(v float64, values ...string) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, labelSet(g.labels, values), formatValue(v))
+	})
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// labelValueEscaper escapes a label value as the text format requires.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelSet renders {name="value",...}, or nothing without labels.
+func labelSet(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, labelValueEscaper.Replace(value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
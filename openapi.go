@@ -0,0 +1,30 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec documents every endpoint under /api. openapi_test.go checks
+// real handler responses against it, so update both together.
+//
+//go:embed api/openapi.json
+var openAPISpec []byte
+
+// explorerPage renders openAPISpec in the browser without any external
+// scripts, so it also works offline.
+//
+//go:embed api/explorer.html
+var explorerPage []byte
+
+// openAPIHandler serves the OpenAPI 3 document.
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(openAPISpec)
+}
+
+// docsHandler serves the API explorer page.
+func docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(explorerPage)
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
+	"github.com/Yoshiofthewire/Password-O-Matic/strength"
+)
+
+// These tests keep api/openapi.json honest: they call the real handlers
+// and validate every response body against the schema the document
+// declares for that path, method, status and content type.
+
+func TestMain(m *testing.M) {
+	if err := loadPolicies(); err != nil {
+		panic(err)
+	}
+	en, err := newDictionary("en", dictOption{Label: "English", MinWords: 1000}, testWords(), nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	set, err := newDictSet([]*dictionary{en}, "")
+	if err != nil {
+		panic(err)
+	}
+	set.source, set.path = sourceFile, cfg.DictFile // as if read from disk
+	dicts.Store(set)
+	m.Run()
+}
+
+// testWords builds a few thousand distinct lower-case words of 3–9
+// letters, enough for every mode without a dictionary file.
+func testWords() []string {
+	syllables := []string{"ba", "ke", "lo", "mi", "nu", "pra", "sto", "vel", "dri", "gan", "hol", "ter", "fis", "zum", "cor", "wen"}
+	var words []string
+	for _, a := range syllables {
+		for _, b := range syllables {
+			for _, c := range syllables {
+				words = append(words, a+b+c)
+			}
+		}
+	}
+	for _, a := range syllables {
+		for _, b := range syllables {
+			words = append(words, a+b)
+		}
+	}
+	return words
+}
+
+type openAPIDoc map[string]any
+
+func loadSpec(t *testing.T) openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("api/openapi.json is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+// lookup walks down a chain of object keys.
+func (d openAPIDoc) lookup(keys ...string) (any, bool) {
+	var cur any = map[string]any(d)
+	for _, k := range keys {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[k]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// resolve follows local $refs such as #/components/schemas/Policy.
+func (d openAPIDoc) resolve(node any) map[string]any {
+	obj, _ := node.(map[string]any)
+	for obj != nil {
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			break
+		}
+		target, found := d.lookup(strings.Split(strings.TrimPrefix(ref, "#/"), "/")...)
+		if !found {
+			return nil
+		}
+		obj, _ = target.(map[string]any)
+	}
+	return obj
+}
+
+// validate checks value against the subset of JSON Schema the document
+// uses: type, enum, properties, required, additionalProperties: false,
+// items and minimum/maximum.
+func (d openAPIDoc) validate(schemaNode any, value any, at string) []string {
+	schema := d.resolve(schemaNode)
+	if schema == nil {
+		return []string{at + ": unresolvable schema"}
+	}
+	var errs []string
+	fail := func(format string, args ...any) {
+		errs = append(errs, at+": "+fmt.Sprintf(format, args...))
+	}
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			fail("want object, got %T", value)
+			return errs
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for _, r := range asList(schema["required"]) {
+			if _, ok := obj[r.(string)]; !ok {
+				fail("missing required property %q", r)
+			}
+		}
+		for k, v := range obj {
+			p, ok := props[k]
+			if !ok {
+				if schema["additionalProperties"] == false {
+					fail("property %q is not in the schema", k)
+				}
+				continue
+			}
+			errs = append(errs, d.validate(p, v, at+"."+k)...)
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			fail("want array, got %T", value)
+			return errs
+		}
+		for i, v := range arr {
+			errs = append(errs, d.validate(schema["items"], v, at+"["+strconv.Itoa(i)+"]")...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			fail("want string, got %T", value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("want boolean, got %T", value)
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			fail("want %s, got %T", schema["type"], value)
+			return errs
+		}
+		if schema["type"] == "integer" && n != float64(int64(n)) {
+			fail("want integer, got %v", n)
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			fail("%v is below the minimum %v", n, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && n > max {
+			fail("%v is above the maximum %v", n, max)
+		}
+	}
+	if enum := asList(schema["enum"]); enum != nil {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+			}
+		}
+		if !found {
+			fail("%v is not one of %v", value, enum)
+		}
+	}
+	return errs
+}
+
+func asList(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+// matchSpec validates a recorded response against the document.
+func matchSpec(t *testing.T, doc openAPIDoc, path, method string, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	status := strconv.Itoa(rec.Code)
+	resp, ok := doc.lookup("paths", path, strings.ToLower(method), "responses", status)
+	if !ok {
+		t.Fatalf("%s %s: status %s is not documented (body %s)", method, path, status, rec.Body)
+	}
+	ct, _, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("%s %s: bad Content-Type %q", method, path, rec.Header().Get("Content-Type"))
+	}
+	respObj, _ := resp.(map[string]any)
+	schema, ok := openAPIDoc(respObj).lookup("content", ct, "schema")
+	if !ok {
+		t.Fatalf("%s %s: %s response with content type %s is not documented", method, path, status, ct)
+	}
+	var bodies [][]byte
+	switch ct {
+	case "application/json", "application/problem+json":
+		bodies = [][]byte{rec.Body.Bytes()}
+	case "application/x-ndjson":
+		// the schema describes each line
+		bodies = bytes.Split(bytes.TrimSuffix(rec.Body.Bytes(), []byte("\n")), []byte("\n"))
+	default:
+		return
+	}
+	for i, line := range bodies {
+		var body any
+		if err := json.Unmarshal(line, &body); err != nil {
+			t.Fatalf("%s %s: response is not JSON: %v", method, path, err)
+		}
+		for _, e := range doc.validate(schema, body, "body"+lineSuffix(ct, i)) {
+			t.Errorf("%s %s %s: %s", method, path, status, e)
+		}
+	}
+}
+
+func lineSuffix(ct string, i int) string {
+	if ct != "application/x-ndjson" {
+		return ""
+	}
+	return " line " + strconv.Itoa(i+1)
+}
+
+func serve(h http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	switch {
+	case method == http.MethodPost && strings.HasPrefix(body, "{"):
+		req.Header.Set("Content-Type", "application/json")
+	case method == http.MethodPost:
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+func TestOpenAPIPasswords(t *testing.T) {
+	doc := loadSpec(t)
+	for _, m := range generator.Modes() {
+		t.Run(m.Name(), func(t *testing.T) {
+			rec := serve(apiHandler, http.MethodGet, "/api/passwords?count=3&mode="+m.Name(), "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			matchSpec(t, doc, "/api/passwords", http.MethodGet, rec)
+		})
+	}
+	cases := []struct {
+		method, query, body string
+		status              int
+	}{
+		{http.MethodGet, "?mode=passphrase&words=5&caps=first&count=2", "", http.StatusOK},
+		{http.MethodGet, "?mode=hex&bytes=16&prefix=pom_&checksum=true", "", http.StatusOK},
+		{http.MethodGet, "?count=5000", "", http.StatusBadRequest},
+		{http.MethodGet, "?count=abc", "", http.StatusBadRequest},
+		{http.MethodGet, "?count=0", "", http.StatusBadRequest},
+		{http.MethodGet, "?mode=pattern&pattern=d~", "", http.StatusBadRequest},
+		{http.MethodPost, "", "mode=random&count=2&exclude_ambiguous=true", http.StatusOK},
+	}
+	for _, c := range cases {
+		rec := serve(apiHandler, c.method, "/api/passwords"+c.query, c.body)
+		if rec.Code != c.status {
+			t.Errorf("%s %s %s: status %d, want %d", c.method, c.query, c.body, rec.Code, c.status)
+			continue
+		}
+		matchSpec(t, doc, "/api/passwords", c.method, rec)
+	}
+}
+
+func TestOpenAPIV2Generate(t *testing.T) {
+	doc := loadSpec(t)
+	cases := []struct {
+		method, body string
+		status       int
+	}{
+		{http.MethodPost, `{"mode":"readability","count":2}`, http.StatusOK},
+		{http.MethodPost, `{"mode":"passphrase","words":5,"caps":"first"}`, http.StatusOK},
+		{http.MethodPost, `{"mode":"nope"}`, http.StatusBadRequest},
+		{http.MethodPost, `{"bogus":1}`, http.StatusBadRequest},
+		{http.MethodPost, `{"count":-1}`, http.StatusBadRequest},
+		{http.MethodPost, `{"count":0}`, http.StatusBadRequest},
+		{http.MethodPost, `{"mode":"pattern","pattern":"d~"}`, http.StatusBadRequest},
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		rec := serve(apiV2GenerateHandler, c.method, "/api/v2/generate", c.body)
+		if rec.Code != c.status {
+			t.Errorf("%s %s: status %d, want %d", c.method, c.body, rec.Code, c.status)
+			continue
+		}
+		// the 405 answer is documented with the POST operation
+		matchSpec(t, doc, "/api/v2/generate", http.MethodPost, rec)
+	}
+}
+
+func TestOpenAPIStream(t *testing.T) {
+	doc := loadSpec(t)
+	cases := []struct {
+		method, query, body string
+		status              int
+		rows                int
+	}{
+		{http.MethodGet, "?count=5&mode=pin", "", http.StatusOK, 5},
+		{http.MethodGet, "?count=3&format=csv", "", http.StatusOK, 4},
+		{http.MethodPost, "?mode=passphrase", "alice\n\nbob\r\ncarol\n", http.StatusOK, 3},
+		{http.MethodGet, "?format=xml", "", http.StatusBadRequest, 1},
+		{http.MethodGet, "?count=0&max_length=3", "", http.StatusBadRequest, 1},
+		{http.MethodPut, "", "", http.StatusMethodNotAllowed, 1},
+	}
+	for _, c := range cases {
+		rec := serve(streamHandler, c.method, "/api/stream"+c.query, c.body)
+		if rec.Code != c.status {
+			t.Errorf("%s %s: status %d, want %d", c.method, c.query, rec.Code, c.status)
+			continue
+		}
+		if n := strings.Count(rec.Body.String(), "\n"); n != c.rows {
+			t.Errorf("%s %s: %d lines, want %d:\n%s", c.method, c.query, n, c.rows, rec.Body)
+		}
+		// the 405 answer is documented with the GET operation
+		method := c.method
+		if method == http.MethodPut {
+			method = http.MethodGet
+		}
+		matchSpec(t, doc, "/api/stream", method, rec)
+	}
+}
+
+func TestOpenAPICheck(t *testing.T) {
+	doc := loadSpec(t)
+	cases := []struct {
+		method, body string
+		status       int
+		score        int
+	}{
+		{http.MethodPost, `{"password":"P@ssw0rd"}`, http.StatusOK, 0},
+		{http.MethodPost, `{"password":"alice1985","user_inputs":["alice@example.com"]}`, http.StatusOK, 1},
+		{http.MethodPost, `{"password":"qwerty13/07/1999zyxwv"}`, http.StatusOK, -1},
+		{http.MethodPost, `{"password":"abcabcabc aaaa Tr0ub4dour"}`, http.StatusOK, -1},
+		{http.MethodPost, `{"password":"kQ8#xv!2Lm9@pZ"}`, http.StatusOK, 4},
+		{http.MethodPost, `{"password":""}`, http.StatusOK, 0},
+		{http.MethodPost, `{"password":"` + strings.Repeat("x", maxCheckLength+1) + `"}`, http.StatusBadRequest, -1},
+		{http.MethodPost, `{"pwd":"x"}`, http.StatusBadRequest, -1},
+		{http.MethodGet, "", http.StatusMethodNotAllowed, -1},
+	}
+	for _, c := range cases {
+		rec := serve(checkHandler, c.method, "/api/check", c.body)
+		if rec.Code != c.status {
+			t.Errorf("%s %.40s: status %d, want %d", c.method, c.body, rec.Code, c.status)
+			continue
+		}
+		if c.score >= 0 {
+			var res strength.Result
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			if res.Score != c.score {
+				t.Errorf("%s: score %d, want %d", c.body, res.Score, c.score)
+			}
+		}
+		// the 405 answer is documented with the POST operation
+		matchSpec(t, doc, "/api/check", http.MethodPost, rec)
+	}
+}
+
+// breachList is a breach.Checker that knows a fixed set of passwords.
+type breachList map[string]int
+
+func (b breachList) Lookup(_ context.Context, pwd string) (int, error) { return b[pwd], nil }
+
+func TestCheckBreached(t *testing.T) {
+	breachChecker = breachList{"kQ8#xv!2Lm9@pZ": 3}
+	defer func() { breachChecker = nil }()
+	doc := loadSpec(t)
+	cases := []struct {
+		password string
+		found    bool
+		score    int
+	}{
+		{"kQ8#xv!2Lm9@pZ", true, 0},
+		{"vB4$np!7Wq2#tY", false, 4},
+	}
+	for _, c := range cases {
+		rec := serve(checkHandler, http.MethodPost, "/api/check", `{"password":"`+c.password+`"}`)
+		matchSpec(t, doc, "/api/check", http.MethodPost, rec)
+		var res checkResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Breach == nil || res.Breach.Found != c.found || res.Score != c.score {
+			t.Errorf("%s: breach %+v, score %d; want found %v, score %d", c.password, res.Breach, res.Score, c.found, c.score)
+		}
+		// the guesses and crack times have to agree with the score
+		if c.found && (res.Guesses > 1000 || res.CrackTimesDisplay.OfflineSlowHashing != "less than a second") {
+			t.Errorf("%s: breached, but %v guesses and %+v", c.password, res.Guesses, res.CrackTimesDisplay)
+		}
+	}
+}
+
+// An existing dictionary.txt was used as is before lists were cleaned, so
+// it must not lose words to the length bounds or change case.
+func TestLegacyDictionaryKeepsWords(t *testing.T) {
+	var b strings.Builder
+	for i := range defaultMinWords {
+		fmt.Fprintf(&b, "Unbelievable%05d\n", i)
+	}
+	b.WriteString("an\nUnbelievable00000\n")
+	path := filepath.Join(t.TempDir(), "dictionary.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved := cfg.DictFile
+	cfg.DictFile = path
+	defer func() { cfg.DictFile = saved }()
+
+	set, err := loadSingleDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := set.list()[0]
+	if d.Words != defaultMinWords+1 || d.Dropped != (dropReport{Duplicates: 1}) {
+		t.Errorf("got %d words, dropped %+v; want %d words and one duplicate", d.Words, d.Dropped, defaultMinWords+1)
+	}
+	if w := d.words[0]; w != "Unbelievable00000" {
+		t.Errorf("first word %q, want it kept as written", w)
+	}
+}
+
+func TestOpenAPIPoliciesAndDerive(t *testing.T) {
+	doc := loadSpec(t)
+	matchSpec(t, doc, "/api/policies", http.MethodGet, serve(policiesHandler, http.MethodGet, "/api/policies", ""))
+	matchSpec(t, doc, "/api/dictionaries", http.MethodGet, serve(dictionariesHandler, http.MethodGet, "/api/dictionaries", ""))
+	matchSpec(t, doc, "/api/status", http.MethodGet, serve(statusHandler, http.MethodGet, "/api/status", ""))
+
+	rec := serve(deriveHandler, http.MethodPost, "/api/derive", `{"master":"m","site":"example.com","login":"a","mode":"pin"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("derive: status %d: %s", rec.Code, rec.Body)
+	}
+	matchSpec(t, doc, "/api/derive", http.MethodPost, rec)
+	matchSpec(t, doc, "/api/derive", http.MethodPost, serve(deriveHandler, http.MethodGet, "/api/derive", ""))
+}
+
+// TestOpenAPIDegraded runs without a dictionary: the modes that need
+// words answer 503 and the others keep working.
+func TestOpenAPIDegraded(t *testing.T) {
+	doc := loadSpec(t)
+	saved := dicts.Load()
+	dicts.Store(degradedDictSet(errors.New("no word list")))
+	defer dicts.Store(saved)
+
+	matchSpec(t, doc, "/api/status", http.MethodGet, serve(statusHandler, http.MethodGet, "/api/status", ""))
+	for _, c := range []struct {
+		query  string
+		status int
+	}{
+		{"?mode=random", http.StatusOK},
+		{"?mode=pin", http.StatusOK},
+		{"?mode=passphrase", http.StatusServiceUnavailable},
+		{"?dict=en", http.StatusBadRequest},
+	} {
+		rec := serve(apiHandler, http.MethodGet, "/api/passwords"+c.query, "")
+		if rec.Code != c.status {
+			t.Errorf("GET /api/passwords%s: status %d, want %d", c.query, rec.Code, c.status)
+			continue
+		}
+		matchSpec(t, doc, "/api/passwords", http.MethodGet, rec)
+	}
+	rec := serve(apiV2GenerateHandler, http.MethodPost, "/api/v2/generate", `{"mode":"normal"}`)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("v2 normal: status %d, want 503", rec.Code)
+	}
+	matchSpec(t, doc, "/api/v2/generate", http.MethodPost, rec)
+}
+
+// TestOpenAPICoversParams catches parameters and modes added to the code
+// but not to the document.
+func TestOpenAPICoversParams(t *testing.T) {
+	doc := loadSpec(t)
+
+	var fields []string
+	typ := reflect.TypeOf(genParams{})
+	for i := 0; i < typ.NumField(); i++ {
+		fields = append(fields, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+	}
+	sort.Strings(fields)
+
+	props, _ := doc.lookup("components", "schemas", "GenerateRequest", "properties")
+	var documented []string
+	for name := range props.(map[string]any) {
+		documented = append(documented, name)
+	}
+	sort.Strings(documented)
+	if !reflect.DeepEqual(fields, documented) {
+		t.Errorf("GenerateRequest properties = %v, genParams fields = %v", documented, fields)
+	}
+
+	params, _ := doc.lookup("paths", "/api/passwords", "get", "parameters")
+	var query []string
+	for _, p := range asList(params) {
+		query = append(query, doc.resolve(p)["name"].(string))
+	}
+	sort.Strings(query)
+	if !reflect.DeepEqual(fields, query) {
+		t.Errorf("GET /api/passwords parameters = %v, genParams fields = %v", query, fields)
+	}
+
+	var modes []any
+	for _, m := range generator.Modes() {
+		modes = append(modes, m.Name())
+	}
+	for _, at := range [][]string{
+		{"components", "schemas", "GenerateRequest", "properties", "mode", "enum"},
+		{"components", "schemas", "GeneratedPassword", "properties", "mode", "enum"},
+		{"components", "schemas", "DeriveRequest", "properties", "mode", "enum"},
+		{"components", "schemas", "DeriveResponse", "properties", "mode", "enum"},
+	} {
+		enum, _ := doc.lookup(at...)
+		if !reflect.DeepEqual(asList(enum), modes) {
+			t.Errorf("%s = %v, registered modes = %v", strings.Join(at, "."), enum, modes)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
+)
+
+// Request limits. The count limit is cfg.MaxCount, defaultMaxCount unless
+// configured.
+const (
+	defaultCount    = 12
+	defaultMaxCount = 1000
+	maxLengthLimit  = 1024
+)
+
+// genParams are the generation settings a request may carry. Pointer
+// fields are overrides that stay nil unless the request sets them.
+type genParams struct {
+	Mode   string `json:"mode,omitempty"`
+	Dict   string `json:"dict,omitempty"`
+	Policy string `json:"policy,omitempty"`
+	Count  *int   `json:"count,omitempty"`
+
+	// policy overrides
+	MinLength *int `json:"min_length,omitempty"`
+	MaxLength *int `json:"max_length,omitempty"`
+	MinUpper  *int `json:"min_upper,omitempty"`
+	MaxUpper  *int `json:"max_upper,omitempty"`
+	MinLower  *int `json:"min_lower,omitempty"`
+	MaxLower  *int `json:"max_lower,omitempty"`
+	MinDigit  *int `json:"min_digit,omitempty"`
+	MaxDigit  *int `json:"max_digit,omitempty"`
+	MinSymbol *int `json:"min_symbol,omitempty"`
+	MaxSymbol *int `json:"max_symbol,omitempty"`
+
+	// mode options
+	Words     int    `json:"words,omitempty"`
+	Sep       string `json:"sep,omitempty"`
+	Caps      string `json:"caps,omitempty"`
+	Length    int    `json:"length,omitempty"`
+	Digits    int    `json:"digits,omitempty"`
+	Symbols   int    `json:"symbols,omitempty"`
+	Bytes     int    `json:"bytes,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	Checksum  bool   `json:"checksum,omitempty"`
+	NoPad     bool   `json:"nopad,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	NoAmbig   bool   `json:"exclude_ambiguous,omitempty"`
+	SymbolSet string `json:"symbol_set,omitempty"`
+	Alphabet  string `json:"alphabet,omitempty"`
+}
+
+// paramError is a request value that failed validation. It is reported to
+// the client as {"error": ..., "field": ...} with status 400.
+type paramError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"error"`
+}
+
+func (e *paramError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+func badParam(field, format string, args ...any) error {
+	return &paramError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// parseParams reads genParams from a query string or form body.
+func parseParams(form url.Values) (genParams, error) {
+	p := genParams{
+		Mode:      form.Get("mode"),
+		Dict:      form.Get("dict"),
+		Policy:    form.Get("policy"),
+		Sep:       form.Get("sep"),
+		Caps:      form.Get("caps"),
+		Prefix:    form.Get("prefix"),
+		Pattern:   form.Get("pattern"),
+		SymbolSet: form.Get("symbol_set"),
+		Alphabet:  form.Get("alphabet"),
+	}
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"words", &p.Words},
+		{"length", &p.Length},
+		{"digits", &p.Digits},
+		{"symbols", &p.Symbols},
+		{"bytes", &p.Bytes},
+	}
+	for _, f := range ints {
+		if v := form.Get(f.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return genParams{}, badParam(f.name, "%q is not a number", v)
+			}
+			*f.dst = n
+		}
+	}
+	overrides := []struct {
+		name string
+		dst  **int
+	}{
+		{"count", &p.Count},
+		{"min_length", &p.MinLength},
+		{"max_length", &p.MaxLength},
+		{"min_upper", &p.MinUpper},
+		{"max_upper", &p.MaxUpper},
+		{"min_lower", &p.MinLower},
+		{"max_lower", &p.MaxLower},
+		{"min_digit", &p.MinDigit},
+		{"max_digit", &p.MaxDigit},
+		{"min_symbol", &p.MinSymbol},
+		{"max_symbol", &p.MaxSymbol},
+	}
+	for _, f := range overrides {
+		if v := form.Get(f.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return genParams{}, badParam(f.name, "%q is not a number", v)
+			}
+			*f.dst = &n
+		}
+	}
+	bools := []struct {
+		name string
+		dst  *bool
+	}{
+		{"checksum", &p.Checksum},
+		{"nopad", &p.NoPad},
+		{"exclude_ambiguous", &p.NoAmbig},
+	}
+	for _, f := range bools {
+		if v := form.Get(f.name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return genParams{}, badParam(f.name, "%q is not a boolean", v)
+			}
+			*f.dst = b
+		}
+	}
+	return p, nil
+}
+
+// count returns the number of passwords asked for, after validation.
+func (p genParams) count() (int, error) {
+	if p.Count == nil {
+		return defaultCount, nil
+	}
+	if n := *p.Count; n < 1 || n > cfg.MaxCount {
+		return 0, badParam("count", "must be between 1 and %d, got %d", cfg.MaxCount, n)
+	}
+	return *p.Count, nil
+}
+
+// generator returns a copy of the chosen dictionary's generator configured
+// by the request: its policy with any overrides applied, and the mode
+// options.
+func (p genParams) generator() (*generator.Generator, error) {
+	g, err := generatorFor(p.Dict, p.Policy)
+	if err != nil {
+		return nil, err
+	}
+	policy := *g.Policy
+	// A policy's Max of 0 means unlimited, but a request sending max_x=0
+	// clearly wants none of that class, so it is forbidden instead.
+	for _, none := range []struct {
+		val   *int
+		chars string
+	}{
+		{p.MaxUpper, generator.UpperLetters},
+		{p.MaxLower, generator.LowerLetters},
+		{p.MaxDigit, generator.Digits},
+		{p.MaxSymbol, generator.Symbols + policy.Symbols},
+	} {
+		if none.val != nil && *none.val == 0 {
+			policy.Forbidden += none.chars
+		}
+	}
+	overrides := []struct {
+		name string
+		val  *int
+		dst  *int
+	}{
+		{"min_length", p.MinLength, &policy.MinLength},
+		{"max_length", p.MaxLength, &policy.MaxLength},
+		{"min_upper", p.MinUpper, &policy.Upper.Min},
+		{"max_upper", p.MaxUpper, &policy.Upper.Max},
+		{"min_lower", p.MinLower, &policy.Lower.Min},
+		{"max_lower", p.MaxLower, &policy.Lower.Max},
+		{"min_digit", p.MinDigit, &policy.Digit.Min},
+		{"max_digit", p.MaxDigit, &policy.Digit.Max},
+		{"min_symbol", p.MinSymbol, &policy.Symbol.Min},
+		{"max_symbol", p.MaxSymbol, &policy.Symbol.Max},
+	}
+	changed := false
+	for _, o := range overrides {
+		if o.val == nil {
+			continue
+		}
+		if *o.val < 0 || *o.val > maxLengthLimit {
+			return nil, badParam(o.name, "must be between 0 and %d, got %d", maxLengthLimit, *o.val)
+		}
+		*o.dst = *o.val
+		changed = true
+	}
+	if changed {
+		if err := policy.Validate(); err != nil {
+			return nil, badParam("policy", "the overrides make the policy unusable: %v", err)
+		}
+		g.Policy = &policy
+	}
+
+	g.Passphrase = generator.PassphraseOptions{Words: p.Words, Separator: p.Sep, Capitalize: p.Caps}
+	g.Pronounceable = generator.PronounceableOptions{Length: p.Length, Digits: p.Digits, Symbols: p.Symbols}
+	g.PIN = generator.PINOptions{Length: p.Length}
+	g.Secret = generator.SecretOptions{Bytes: p.Bytes, Prefix: p.Prefix, Checksum: p.Checksum, NoPadding: p.NoPad}
+	g.Pattern = p.Pattern
+	g.Charset = generator.CharsetOptions{ExcludeAmbiguous: p.NoAmbig, Symbols: p.SymbolSet, Alphabet: p.Alphabet}
+	return g, nil
+}
+
+// writeAPIError writes err as {"error": ..., "field": ...}. Parameter
+// errors carry the offending field.
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	body, ok := err.(*paramError)
+	if !ok {
+		body = &paramError{Message: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ------------------------------------------------------------
+// Dictionary hot reload
+// ------------------------------------------------------------
+
+const (
+	// dictPollInterval is how often the files are checked where they
+	// cannot be watched.
+	dictPollInterval = 2 * time.Second
+	// dictSettle is how long the files must stay quiet before a reload,
+	// so that a list still being written is not read half-way.
+	dictSettle = 500 * time.Millisecond
+)
+
+// reloadMu keeps reloads from overlapping; requests never take it.
+var reloadMu sync.Mutex
+
+// reloadFailure is a reload that left the previous lists in place.
+type reloadFailure struct {
+	At    time.Time `json:"at"`
+	Error string    `json:"error"`
+}
+
+// lastReloadFailure is cleared by the next successful reload.
+var lastReloadFailure atomic.Pointer[reloadFailure]
+
+// reloadDictionaries loads every list again and swaps the new set in only
+// if all of them pass validation. Otherwise the current set keeps serving.
+func reloadDictionaries(reason string) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	set, err := loadDictionaries()
+	if err != nil {
+		log.Printf("Dictionary reload (%s) failed, keeping the current dictionaries: %v", reason, err)
+		lastReloadFailure.Store(&reloadFailure{At: time.Now().UTC(), Error: err.Error()})
+		return
+	}
+	dicts.Store(set)
+	lastReloadFailure.Store(nil)
+	log.Printf("Dictionaries reloaded (%s): %s", reason, strings.Join(set.names, ", "))
+}
+
+// watchDictionaries reloads the dictionaries on SIGHUP and whenever their
+// files change, using inotify where available and polling otherwise.
+func watchDictionaries() {
+	dir, match := dictFiles()
+	events := make(chan struct{}, 1)
+	if err := watchDir(dir, match, events); err != nil {
+		log.Printf("Cannot watch %s (%v), checking it every %s instead", dir, err, dictPollInterval)
+		go pollDir(dir, match, events)
+	}
+	changed := make(chan struct{}, 1)
+	go settle(events, changed)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-hup:
+				reloadDictionaries("SIGHUP")
+			case <-changed:
+				reloadDictionaries("files changed")
+			}
+		}
+	}()
+}
+
+// dictFiles returns the directory holding the word lists and which of its
+// entries matter: cfg.DictDir's lists and manifest, or just cfg.DictFile.
+func dictFiles() (dir string, match func(name string) bool) {
+	if info, err := os.Stat(cfg.DictDir); err == nil && info.IsDir() {
+		return cfg.DictDir, func(name string) bool {
+			return filepath.Ext(name) == ".txt" || name == dictManifest
+		}
+	}
+	return filepath.Dir(cfg.DictFile), func(name string) bool {
+		return name == filepath.Base(cfg.DictFile)
+	}
+}
+
+// notify sends on ch without blocking; one pending signal is enough.
+func notify(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// settle passes events on to changed once no more have arrived for
+// dictSettle.
+func settle(events <-chan struct{}, changed chan<- struct{}) {
+	for range events {
+		quiet := time.NewTimer(dictSettle)
+		for waiting := true; waiting; {
+			select {
+			case <-events:
+				quiet.Reset(dictSettle)
+			case <-quiet.C:
+				waiting = false
+			}
+		}
+		notify(changed)
+	}
+}
+
+// pollDir signals events whenever the name, size or modification time of
+// a matching file in dir changes.
+func pollDir(dir string, match func(string) bool, events chan<- struct{}) {
+	last := dirState(dir, match)
+	for range time.Tick(dictPollInterval) {
+		if cur := dirState(dir, match); cur != last {
+			last = cur
+			notify(events)
+		}
+	}
+}
+
+func dirState(dir string, match func(string) bool) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		if !match(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // removed since ReadDir
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", e.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/breach"
+)
+
+// breachChecker screens generated passwords and reports hits in
+// /api/check. It stays nil when no breach data is configured.
+var breachChecker breach.Checker
+
+// breachTimeout bounds one lookup, which may be a range API request.
+const breachTimeout = 10 * time.Second
+
+// loadBreachData sets up breachChecker from the configuration:
+//
+//	breach_file  a sorted Pwned Passwords hash file, or a Bloom filter
+//	             built from one with build-bloom
+//	breach_api   base URL of a k-anonymity range API, such as
+//	             https://api.pwnedpasswords.com or a local mirror
+//	breach_hash  sha1 (default) or ntlm, for the range API
+//
+// With both set, the file is consulted first.
+func loadBreachData() error {
+	var checkers []breach.Checker
+	if path := cfg.BreachFile; path != "" {
+		isBloom, err := breach.IsBloomFile(path)
+		if err != nil {
+			return err
+		}
+		if isBloom {
+			b, err := breach.LoadBloom(path)
+			if err != nil {
+				return err
+			}
+			log.Printf("Screening against %s Bloom filter %s", b.Type(), path)
+			checkers = append(checkers, b)
+		} else {
+			f, err := breach.OpenHashFile(path)
+			if err != nil {
+				return err
+			}
+			log.Printf("Screening against %s hash file %s", f.Type(), path)
+			checkers = append(checkers, f)
+		}
+	}
+	if url := cfg.BreachAPI; url != "" {
+		typ, err := breach.ParseHashType(cfg.BreachHash)
+		if err != nil {
+			return fmt.Errorf("breach_hash: %w", err)
+		}
+		log.Printf("Screening against range API %s (%s)", redactURL(url), typ)
+		checkers = append(checkers, &breach.RangeClient{BaseURL: url, Type: typ, UserAgent: "Password-O-Matic"})
+	}
+	if len(checkers) > 0 {
+		breachChecker = breach.Any(checkers...)
+	}
+	return nil
+}
+
+// lookupBreach returns how often pwd appears in the breach data.
+func lookupBreach(ctx context.Context, pwd string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, breachTimeout)
+	defer cancel()
+	return breachChecker.Lookup(ctx, pwd)
+}
+
+// screenBreached is every dictionary generator's Screen when breach data
+// is configured: passwords found in it are regenerated. A failed lookup
+// lets the password through so that an unreachable range API does not stop
+// generation; generated passwords are random and practically never
+// breached anyway. Only a cancelled request stops generation.
+func screenBreached(ctx context.Context, pwd string) (bool, error) {
+	n, err := lookupBreach(ctx, pwd)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		log.Printf("Breach screening skipped: %v", err)
+		return false, nil
+	}
+	return n > 0, nil
+}
+
+// buildBloomFile writes a Bloom filter of the hash file in to out, sized
+// from the file for a false-positive rate of fp.
+func buildBloomFile(in, out string, fp float64) error {
+	f, err := breach.OpenHashFile(in)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	r, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	st, err := r.Stat()
+	if err != nil {
+		return err
+	}
+	// "HASH:COUNT\r\n" with a count of a few digits
+	lineLen := int64(40 + 8)
+	if f.Type() == breach.NTLM {
+		lineLen = 32 + 8
+	}
+	b, err := breach.BuildBloom(r, int(max(1, st.Size()/lineLen)), fp)
+	if err != nil {
+		return err
+	}
+	w, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if _, err := b.WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yoshiofthewire/Password-O-Matic/generator"
+)
+
+// Streaming limits. A stream has its own, much larger, count limit than
+// /api/passwords because nothing is held in memory.
+const (
+	maxStreamCount = 1_000_000
+	maxStreamBody  = 64 << 20 // POSTed IDs
+	maxStreamID    = 1024
+	flushRows      = 1000
+	flushInterval  = 500 * time.Millisecond
+)
+
+// streamRow is one generated password in a stream. ID is the caller's
+// row ID, or the 1-based row number when none were POSTed.
+type streamRow struct {
+	ID       string  `json:"id"`
+	Password string  `json:"password"`
+	Mode     string  `json:"mode"`
+	Fallback bool    `json:"fallback"`
+	Entropy  float64 `json:"entropy"`
+}
+
+var streamCSVHeader = []string{"id", "password", "mode", "fallback", "entropy"}
+
+// rowWriter encodes rows in one of the stream formats.
+type rowWriter interface {
+	write(streamRow) error
+	// fail reports an error after rows were already sent.
+	fail(error) error
+	flush() error
+}
+
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) write(row streamRow) error { return n.enc.Encode(row) }
+
+func (n *ndjsonWriter) fail(err error) error {
+	return n.enc.Encode(paramError{Message: err.Error()})
+}
+
+func (n *ndjsonWriter) flush() error { return n.w.Flush() }
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) write(row streamRow) error {
+	return c.w.Write([]string{row.ID, row.Password, row.Mode, strconv.FormatBool(row.Fallback), strconv.FormatFloat(row.Entropy, 'f', -1, 64)})
+}
+
+// fail has no row to use in CSV, so the error goes in the trailer only.
+func (c *csvWriter) fail(error) error { return nil }
+
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// streamFormat picks the output format from ?format= or the Accept header.
+func streamFormat(r *http.Request) (string, error) {
+	switch f := r.URL.Query().Get("format"); f {
+	case "ndjson", "csv":
+		return f, nil
+	case "":
+		if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+			return "csv", nil
+		}
+		return "ndjson", nil
+	default:
+		return "", badParam("format", "must be ndjson or csv, got %q", f)
+	}
+}
+
+// streamHandler writes passwords as NDJSON or CSV rows while generating
+// them, so the batch size is not bounded by memory. Settings come from the
+// query string, as for /api/passwords. A POST body lists one row ID per
+// line (e.g. usernames) and gets one password per ID; otherwise count rows
+// are numbered from 1. Generation stops when the client goes away.
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		writeAPIError(w, http.StatusMethodNotAllowed, errors.New("use GET or POST"))
+		return
+	}
+	params, err := parseParams(r.URL.Query())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	format, err := streamFormat(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	mode := params.Mode
+	if _, ok := generator.Lookup(mode); !ok {
+		mode = "normal"
+	}
+	g, err := params.generator()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// next returns the next row ID, or ok=false at the end of the input.
+	var next func() (id string, ok bool, err error)
+	if r.Method == http.MethodPost {
+		ids := bufio.NewScanner(http.MaxBytesReader(w, r.Body, maxStreamBody))
+		ids.Buffer(make([]byte, 0, 4096), maxStreamID+2)
+		n := 0
+		next = func() (string, bool, error) {
+			for ids.Scan() {
+				id := strings.TrimSpace(ids.Text())
+				if id == "" {
+					continue
+				}
+				if n++; n > maxStreamCount {
+					return "", false, fmt.Errorf("more than %d IDs", maxStreamCount)
+				}
+				return id, true, nil
+			}
+			if errors.Is(ids.Err(), bufio.ErrTooLong) {
+				return "", false, fmt.Errorf("an ID is longer than %d bytes", maxStreamID)
+			}
+			return "", false, ids.Err()
+		}
+	} else {
+		count := defaultCount
+		if params.Count != nil {
+			count = *params.Count
+		}
+		if count < 1 || count > maxStreamCount {
+			writeAPIError(w, http.StatusBadRequest, badParam("count", "must be between 1 and %d, got %d", maxStreamCount, count))
+			return
+		}
+		n := 0
+		next = func() (string, bool, error) {
+			if n == count {
+				return "", false, nil
+			}
+			n++
+			return strconv.Itoa(n), true, nil
+		}
+	}
+
+	// Generate the first row before committing to a 200, so that a policy
+	// the mode cannot satisfy is still reported as a normal error.
+	id, ok, err := next()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	var first generator.Result
+	if ok {
+		if first, err = generate(r.Context(), g, mode); err != nil {
+			writeAPIError(w, generateErrorStatus(err), fmt.Errorf("could not generate password: %w", err))
+			return
+		}
+	}
+
+	w.Header().Set("Trailer", "X-Stream-Error")
+	w.Header().Set("Cache-Control", "no-store")
+	buf := bufio.NewWriterSize(w, 32<<10)
+	var out rowWriter
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := &csvWriter{w: csv.NewWriter(buf)}
+		cw.w.Write(streamCSVHeader)
+		out = cw
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		out = &ndjsonWriter{w: buf, enc: json.NewEncoder(buf)}
+	}
+	rc := http.NewResponseController(w)
+	if r.Method == http.MethodPost {
+		// Keep reading IDs after the first rows are sent. HTTP/2 always
+		// allows this; HTTP/1.1 has to opt in.
+		rc.EnableFullDuplex()
+	}
+	flush := func() error {
+		if err := out.flush(); err != nil {
+			return err
+		}
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	// The server's read and write timeouts are sized for ordinary
+	// requests. A stream gets them afresh after every flush, so it can run
+	// for as long as it keeps making progress.
+	extendDeadlines := func() {
+		now := time.Now()
+		if d := time.Duration(cfg.WriteTimeout); d > 0 {
+			rc.SetWriteDeadline(now.Add(d))
+		}
+		// also for GET: HTTP/1.1 watches for the client going away with a
+		// read that would otherwise time out and cancel the request
+		if d := time.Duration(cfg.ReadTimeout); d > 0 {
+			rc.SetReadDeadline(now.Add(d))
+		}
+	}
+	extendDeadlines()
+
+	rows, lastFlush := 0, time.Now()
+	fail := func(err error) {
+		log.Printf("stream: stopped after %d rows: %v", rows, err)
+		w.Header().Set("X-Stream-Error", err.Error())
+		out.fail(err)
+		flush()
+	}
+	for ok {
+		if err := r.Context().Err(); err != nil {
+			return // client went away
+		}
+		res := first
+		if rows > 0 {
+			if res, err = generate(r.Context(), g, mode); err != nil {
+				fail(fmt.Errorf("could not generate password: %w", err))
+				return
+			}
+		}
+		row := streamRow{ID: id, Password: res.Password, Mode: res.Mode, Fallback: res.FellBack, Entropy: roundBits(res.Entropy)}
+		if err := out.write(row); err != nil {
+			return
+		}
+		rows++
+		if rows%flushRows == 0 || time.Since(lastFlush) >= flushInterval {
+			if err := flush(); err != nil {
+				return // the client is gone
+			}
+			lastFlush = time.Now()
+			extendDeadlines()
+		}
+		if id, ok, err = next(); err != nil {
+			fail(err)
+			return
+		}
+	}
+	flush()
+}
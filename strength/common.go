@@ -0,0 +1,23 @@
+package strength
+
+// commonPasswords are the most common passwords in public breach corpora,
+// most common first, so a password's position is its rank.
+var commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234", "111111", "1234567", "dragon",
+	"123123", "baseball", "abc123", "football", "monkey", "letmein", "696969", "shadow", "master", "666666",
+	"qwertyuiop", "123321", "mustang", "1234567890", "michael", "654321", "superman", "1qaz2wsx", "7777777", "121212",
+	"000000", "qazwsx", "123qwe", "killer", "trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter",
+	"buster", "soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou", "2000", "charlie",
+	"robert", "thomas", "hockey", "ranger", "daniel", "starwars", "maverick", "112233", "george", "computer",
+	"michelle", "jessica", "pepper", "1111", "zxcvbn", "555555", "11111111", "131313", "freedom", "777777",
+	"pass", "maggie", "159753", "aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda", "summer",
+	"love", "ashley", "nicole", "chelsea", "biteme", "matthew", "access", "yankees", "987654321", "dallas",
+	"austin", "thunder", "taylor", "matrix", "william", "corvette", "hello1", "martin", "heather", "secret1",
+	"welcome", "admin", "passw0rd", "password1", "password123", "qwerty123", "1q2w3e4r", "1q2w3e", "q1w2e3r4", "zaq12wsx",
+	"abcd1234", "aa123456", "123abc", "secret", "whatever", "hello", "hello123", "login", "princess1", "solo",
+	"starwars1", "flower", "hottie", "loveme", "lovely", "babygirl", "666666666", "888888", "999999", "101010",
+	"asdfghjkl", "asdf", "qwer1234", "1qazxsw2", "google", "liverpool", "arsenal", "chelsea1", "samsung", "apple",
+	"orange", "banana", "cookie", "butterfly", "purple", "jordan23", "charlie1", "superman1", "iloveyou1", "football1",
+	"baseball1", "welcome1", "letmein1", "master1", "dragon1", "monkey1", "shadow1", "sunshine1", "qwerty1", "abc12345",
+	"changeme", "default", "guest", "root", "toor", "administrator", "test", "test123", "temp", "p@ssw0rd",
+}
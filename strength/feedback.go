@@ -0,0 +1,101 @@
+package strength
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ------------------------------------------------------------
+// Feedback
+// ------------------------------------------------------------
+
+// feedback explains a weak score using the longest match, which is the
+// part of the password that did the most damage. Passwords scoring 3 or
+// more get no feedback.
+func feedback(score int, seq []Match) Feedback {
+	if len(seq) == 0 {
+		return Feedback{Suggestions: []string{
+			"Use a few words, avoid common phrases",
+			"No need for symbols, digits, or uppercase letters",
+		}}
+	}
+	if score > 2 {
+		return Feedback{Suggestions: []string{}}
+	}
+	longest := seq[0]
+	for _, m := range seq[1:] {
+		if len([]rune(m.Token)) > len([]rune(longest.Token)) {
+			longest = m
+		}
+	}
+	fb := matchFeedback(longest, len(seq) == 1)
+	fb.Suggestions = append([]string{"Add another word or two. Uncommon words are better."}, fb.Suggestions...)
+	return fb
+}
+
+func matchFeedback(m Match, soleMatch bool) Feedback {
+	switch m.Pattern {
+	case "dictionary":
+		return dictionaryFeedback(m, soleMatch)
+	case "spatial":
+		warning := "Short keyboard patterns are easy to guess"
+		if m.Turns == 1 {
+			warning = "Straight rows of keys are easy to guess"
+		}
+		return Feedback{warning, []string{"Use a longer keyboard pattern with more turns"}}
+	case "repeat":
+		warning := `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+		if len([]rune(m.BaseToken)) == 1 {
+			warning = `Repeats like "aaa" are easy to guess`
+		}
+		return Feedback{warning, []string{"Avoid repeated words and characters"}}
+	case "sequence":
+		return Feedback{"Sequences like abc or 6543 are easy to guess", []string{"Avoid sequences"}}
+	case "year":
+		return Feedback{"Recent years are easy to guess", []string{
+			"Avoid recent years",
+			"Avoid years that are associated with you",
+		}}
+	case "date":
+		return Feedback{"Dates are often easy to guess", []string{"Avoid dates and years that are associated with you"}}
+	}
+	return Feedback{Suggestions: []string{}}
+}
+
+func dictionaryFeedback(m Match, soleMatch bool) Feedback {
+	var fb Feedback
+	switch m.Dictionary {
+	case "passwords":
+		switch {
+		case soleMatch && !m.L33t && !m.Reversed && m.Rank <= 10:
+			fb.Warning = "This is a top-10 common password"
+		case soleMatch && !m.L33t && !m.Reversed && m.Rank <= 100:
+			fb.Warning = "This is a top-100 common password"
+		case soleMatch:
+			fb.Warning = "This is a very common password"
+		default:
+			fb.Warning = "This is similar to a commonly used password"
+		}
+	case "user_inputs":
+		fb.Warning = "Names and personal details are easy to guess"
+	case "words":
+		if soleMatch {
+			fb.Warning = "A word by itself is easy to guess"
+		}
+	}
+	fb.Suggestions = []string{}
+	runes := []rune(m.Token)
+	switch {
+	case strings.ToUpper(m.Token) == m.Token && strings.ToLower(m.Token) != m.Token:
+		fb.Suggestions = append(fb.Suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+	case unicode.IsUpper(runes[0]):
+		fb.Suggestions = append(fb.Suggestions, "Capitalization doesn't help very much")
+	}
+	if m.Reversed && len(runes) >= 4 {
+		fb.Suggestions = append(fb.Suggestions, "Reversed words aren't much harder to guess")
+	}
+	if m.L33t {
+		fb.Suggestions = append(fb.Suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+	}
+	return fb
+}
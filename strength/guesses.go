@@ -0,0 +1,303 @@
+package strength
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// ------------------------------------------------------------
+// Guess estimates and the cheapest match sequence
+// ------------------------------------------------------------
+
+// Every figure here is log10 of a guess count, since brute-force runs of
+// long passwords overflow a float64.
+const (
+	// bruteforceCardinality is the guesses per brute-forced character.
+	bruteforceCardinality = 10
+	// A match shorter than the whole password costs at least this much,
+	// so a password is not split into many tiny cheap pieces.
+	minSubmatchGuessesSingleChar = 10
+	minSubmatchGuessesMultiChar  = 50
+	// minGuessesBeforeGrowingSequence is the extra cost per additional
+	// match in a sequence, for the attacker not knowing how many there are.
+	minGuessesBeforeGrowingSequence = 10000
+	// minYearSpace keeps years close to today from counting as a handful
+	// of guesses.
+	minYearSpace = 20
+)
+
+// estimate fills in m.lg for a match found in a password of n runes.
+func (mt matcher) estimate(m *Match, n int) {
+	var lg float64
+	switch m.Pattern {
+	case "dictionary":
+		lg = math.Log10(float64(m.Rank)) + uppercaseVariations(m.Token) + l33tVariations(m)
+		if m.Reversed {
+			lg += math.Log10(2)
+		}
+	case "spatial":
+		lg = spatialGuesses(m)
+	case "sequence":
+		lg = sequenceGuesses(m)
+	case "repeat":
+		// m.lg already holds the base token's guesses
+		lg = m.lg + math.Log10(float64(m.RepeatCount))
+	case "date":
+		g := float64(max(abs(m.Year-mt.year), minYearSpace)) * 365
+		if m.Separator != "" {
+			g *= 4
+		}
+		lg = math.Log10(g)
+	case "year":
+		lg = math.Log10(float64(max(abs(m.Year-mt.year), minYearSpace)))
+	}
+	if tokenLen := len([]rune(m.Token)); tokenLen < n {
+		min := float64(minSubmatchGuessesMultiChar)
+		if tokenLen == 1 {
+			min = minSubmatchGuessesSingleChar
+		}
+		lg = math.Max(lg, math.Log10(min))
+	}
+	m.lg = math.Max(lg, 0)
+}
+
+// log10Binomial returns log10 of n choose k.
+func log10Binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return math.Inf(-1)
+	}
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return (a - b - c) / math.Ln10
+}
+
+// log10Sum returns log10(10^a + 10^b).
+func log10Sum(a, b float64) float64 {
+	if math.IsInf(a, -1) {
+		return b
+	}
+	if math.IsInf(b, -1) {
+		return a
+	}
+	hi, lo := math.Max(a, b), math.Min(a, b)
+	if hi-lo > 16 {
+		return hi // lo is below float64 precision
+	}
+	return hi + math.Log10(1+math.Pow(10, lo-hi))
+}
+
+// variations returns log10 of the ways to mix a of one kind with b of
+// another when at least one of the rarer kind is present.
+func variations(a, b int) float64 {
+	sum := math.Inf(-1)
+	for i := 1; i <= min(a, b); i++ {
+		sum = log10Sum(sum, log10Binomial(a+b, i))
+	}
+	return sum
+}
+
+// uppercaseVariations is the extra cost of the token's capitalisation:
+// none for lower case, a factor of two for the usual patterns (first or
+// last letter, or all capitals), and every mix otherwise.
+func uppercaseVariations(token string) float64 {
+	upper, lower := 0, 0
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 0
+	}
+	runes := []rune(token)
+	firstOnly := unicode.IsUpper(runes[0]) && upper == 1
+	lastOnly := unicode.IsUpper(runes[len(runes)-1]) && upper == 1
+	if firstOnly || lastOnly || lower == 0 {
+		return math.Log10(2)
+	}
+	return variations(upper, lower)
+}
+
+// l33tVariations is the extra cost of the substitutions in a l33t match:
+// a factor of two when a letter is always substituted, and every mix of
+// substituted and plain when both appear.
+func l33tVariations(m *Match) float64 {
+	if !m.L33t {
+		return 0
+	}
+	lg := 0.0
+	lower := strings.ToLower(m.Token)
+	for subbed, plain := range m.Sub {
+		s := strings.Count(lower, subbed)
+		u := strings.Count(lower, plain)
+		if s == 0 || u == 0 {
+			lg += math.Log10(2)
+		} else {
+			lg += variations(s, u)
+		}
+	}
+	return lg
+}
+
+// spatialGuesses counts the walks of the token's length with up to its
+// number of turns, starting anywhere on the keyboard.
+func spatialGuesses(m *Match) float64 {
+	var g adjacencyGraph
+	for _, candidate := range graphs {
+		if candidate.name == m.Graph {
+			g = candidate
+		}
+	}
+	length := len([]rune(m.Token))
+	lg := math.Inf(-1)
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(m.Turns, i-1); j++ {
+			lg = log10Sum(lg, log10Binomial(i-1, j-1)+math.Log10(g.keys)+float64(j)*math.Log10(g.degree))
+		}
+	}
+	if m.Shifted > 0 {
+		unshifted := length - m.Shifted
+		if unshifted == 0 {
+			lg += math.Log10(2)
+		} else {
+			lg += variations(m.Shifted, unshifted)
+		}
+	}
+	return lg
+}
+
+// sequenceGuesses rates obvious starting points ("a", "1", "9") lower and
+// descending sequences higher.
+func sequenceGuesses(m *Match) float64 {
+	first := []rune(m.Token)[0]
+	var base float64
+	switch {
+	case strings.ContainsRune("aAzZ019", first):
+		base = 4
+	case first >= '0' && first <= '9':
+		base = 10
+	default:
+		base = 26
+	}
+	if !m.Ascending {
+		base *= 2
+	}
+	return math.Log10(base * float64(len([]rune(m.Token))))
+}
+
+// ------------------------------------------------------------
+// The cheapest sequence
+// ------------------------------------------------------------
+
+// step is the best way found to cover pw[:k+1] with l matches.
+type step struct {
+	l  int
+	m  Match
+	pi float64 // log10 of the product of the matches' guesses
+	g  float64 // log10 of the total guesses, see mostGuessable
+}
+
+// mostGuessable returns the sequence of non-overlapping matches, with
+// brute force between them, that minimises
+//
+//	l! * product(guesses) + minGuessesBeforeGrowingSequence^(l-1)
+//
+// over sequences of l matches, as zxcvbn does, and that minimum.
+func (mt matcher) mostGuessable(pw []rune, matches []Match) (float64, []Match) {
+	n := len(pw)
+	if n == 0 {
+		return 0, []Match{}
+	}
+	byEnd := make([][]Match, n)
+	for _, m := range matches {
+		mt.estimate(&m, n)
+		byEnd[m.J] = append(byEnd[m.J], m)
+	}
+	logFactorial := make([]float64, n+2)
+	for l := 2; l < len(logFactorial); l++ {
+		logFactorial[l] = logFactorial[l-1] + math.Log10(float64(l))
+	}
+	growth := math.Log10(minGuessesBeforeGrowingSequence)
+
+	// optimal[k] holds the best step ending at k for each match count l
+	// that is not beaten by a step with fewer matches.
+	optimal := make([][]step, n)
+	update := func(m Match, pi float64, l int) {
+		k := m.J
+		pi += m.lg
+		g := log10Sum(logFactorial[l]+pi, float64(l-1)*growth)
+		same := -1
+		for i, other := range optimal[k] {
+			if other.l <= l && other.g <= g {
+				return // a sequence with no more matches is at least as cheap
+			}
+			if other.l == l {
+				same = i
+			}
+		}
+		if same >= 0 {
+			optimal[k][same] = step{l: l, m: m, pi: pi, g: g}
+		} else {
+			optimal[k] = append(optimal[k], step{l: l, m: m, pi: pi, g: g})
+		}
+	}
+	// Brute-force runs are priced without building their token, which
+	// is filled in for the winning sequence only.
+	bruteforce := func(i, j int) Match {
+		m := Match{Pattern: "bruteforce", I: i, J: j}
+		// one above the submatch minimums, so brute force never wins a
+		// tie with a real pattern
+		min := float64(minSubmatchGuessesMultiChar + 1)
+		if i == j {
+			min = minSubmatchGuessesSingleChar + 1
+		}
+		m.lg = math.Max(float64(j-i+1)*math.Log10(bruteforceCardinality), math.Log10(min))
+		return m
+	}
+	for k := 0; k < n; k++ {
+		for _, m := range byEnd[k] {
+			if m.I == 0 {
+				update(m, 0, 1)
+				continue
+			}
+			for _, prev := range optimal[m.I-1] {
+				update(m, prev.pi, prev.l+1)
+			}
+		}
+		update(bruteforce(0, k), 0, 1)
+		for i := 1; i <= k; i++ {
+			m := bruteforce(i, k)
+			for _, prev := range optimal[i-1] {
+				// two brute-force runs in a row are one longer run
+				if prev.m.Pattern != "bruteforce" {
+					update(m, prev.pi, prev.l+1)
+				}
+			}
+		}
+	}
+
+	best := optimal[n-1][0]
+	for _, s := range optimal[n-1][1:] {
+		if s.g < best.g || (s.g == best.g && s.l < best.l) {
+			best = s
+		}
+	}
+	seq := make([]Match, best.l)
+	for k, l := n-1, best.l; k >= 0; l-- {
+		var m Match
+		for _, s := range optimal[k] {
+			if s.l == l {
+				m = s.m
+			}
+		}
+		m.Token = string(pw[m.I : m.J+1])
+		seq[l-1] = m
+		k = m.I - 1
+	}
+	return best.g, seq
+}
@@ -0,0 +1,92 @@
+package strength
+
+import "strings"
+
+// ------------------------------------------------------------
+// Keyboard adjacency graphs for spatial matching
+// ------------------------------------------------------------
+
+// adjacencyGraph maps a character to its neighbouring keys, one entry per
+// direction in a fixed order so that a change of direction is a turn.
+// An entry holds the unshifted and shifted character of the key, or is
+// empty where there is no key.
+type adjacencyGraph struct {
+	name      string
+	neighbors map[rune][]string
+	// keys and degree feed the guess estimate: the number of starting
+	// keys and the average number of neighbours per key.
+	keys   float64
+	degree float64
+}
+
+// qwertyRows is a US keyboard with the unshifted and shifted character of
+// each key. The number row sits half a key to the left of the letters.
+var qwertyRows = []string{
+	"`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+",
+	"qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|",
+	"aA sS dD fF gG hH jJ kK lL ;: '\"",
+	"zZ xX cC vV bB nN mM ,< .> /?",
+}
+
+// keypadRows is a numeric keypad; a space is a gap.
+var keypadRows = []string{
+	" /*-",
+	"789+",
+	"456",
+	"123",
+	" 0.",
+}
+
+var graphs = []adjacencyGraph{
+	buildGraph("qwerty", qwertyGrid(), [][2]int{{-1, 0}, {0, -1}, {1, -1}, {1, 0}, {0, 1}, {-1, 1}}),
+	buildGraph("keypad", keypadGrid(), [][2]int{{-1, 0}, {-1, -1}, {0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}}),
+}
+
+type gridPos struct{ x, y int }
+
+// qwertyGrid lays the keyboard out on slanted coordinates: the keys above
+// a key are at x and x+1, the keys below at x-1 and x.
+func qwertyGrid() map[gridPos]string {
+	grid := map[gridPos]string{}
+	for y, row := range qwertyRows {
+		for x, key := range strings.Fields(row) {
+			if y == 0 {
+				x-- // "1" sits above "q"
+			}
+			grid[gridPos{x, y}] = key
+		}
+	}
+	return grid
+}
+
+func keypadGrid() map[gridPos]string {
+	grid := map[gridPos]string{}
+	for y, row := range keypadRows {
+		for x, key := range row {
+			if key != ' ' {
+				grid[gridPos{x, y}] = string(key)
+			}
+		}
+	}
+	return grid
+}
+
+func buildGraph(name string, grid map[gridPos]string, dirs [][2]int) adjacencyGraph {
+	g := adjacencyGraph{name: name, neighbors: map[rune][]string{}}
+	total := 0
+	for pos, key := range grid {
+		adj := make([]string, len(dirs))
+		for i, d := range dirs {
+			if n, ok := grid[gridPos{pos.x + d[0], pos.y + d[1]}]; ok {
+				adj[i] = n
+				total++
+			}
+		}
+		for _, r := range key {
+			g.neighbors[r] = adj
+		}
+	}
+	g.keys = float64(len(grid))
+	g.degree = float64(total) / float64(len(grid))
+	return g
+}
@@ -0,0 +1,572 @@
+package strength
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ------------------------------------------------------------
+// Pattern matching
+// ------------------------------------------------------------
+
+// matcher finds every pattern in a password. Matches may overlap; the
+// search in guesses.go picks the cheapest non-overlapping sequence.
+type matcher struct {
+	dicts []rankedDict
+	year  int
+}
+
+func (m matcher) omnimatch(pw []rune) []Match {
+	var matches []Match
+	matches = append(matches, m.dictionaryMatches(pw)...)
+	matches = append(matches, m.reverseDictionaryMatches(pw)...)
+	matches = append(matches, m.l33tMatches(pw)...)
+	matches = append(matches, spatialMatches(pw)...)
+	matches = append(matches, sequenceMatches(pw)...)
+	matches = append(matches, m.repeatMatches(pw)...)
+	matches = append(matches, m.dateMatches(pw)...)
+	matches = append(matches, m.yearMatches(pw)...)
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].I != matches[b].I {
+			return matches[a].I < matches[b].I
+		}
+		return matches[a].J < matches[b].J
+	})
+	return matches
+}
+
+func lowerRunes(pw []rune) []rune {
+	lower := make([]rune, len(pw))
+	for i, r := range pw {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+// ----- dictionary -----
+
+// dictionaryMatches finds every substring that is a word in one of the
+// dictionaries, case-insensitively.
+func (m matcher) dictionaryMatches(pw []rune) []Match {
+	lower := lowerRunes(pw)
+	var matches []Match
+	for _, d := range m.dicts {
+		for i := range lower {
+			for j := i; j < len(lower) && j-i < d.maxLen; j++ {
+				word := string(lower[i : j+1])
+				rank, ok := d.ranks[word]
+				if !ok {
+					continue
+				}
+				matches = append(matches, Match{
+					Pattern:     "dictionary",
+					I:           i,
+					J:           j,
+					Token:       string(pw[i : j+1]),
+					Dictionary:  d.name,
+					MatchedWord: word,
+					Rank:        rank,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// reverseDictionaryMatches finds words spelt backwards.
+func (m matcher) reverseDictionaryMatches(pw []rune) []Match {
+	rev := make([]rune, len(pw))
+	for i, r := range pw {
+		rev[len(pw)-1-i] = r
+	}
+	var matches []Match
+	for _, match := range m.dictionaryMatches(rev) {
+		token := []rune(match.Token)
+		if string(token) == reverse(token) {
+			continue // palindromes were found the right way round
+		}
+		match.Token = reverse(token)
+		match.I, match.J = len(pw)-1-match.J, len(pw)-1-match.I
+		match.Reversed = true
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+func reverse(r []rune) string {
+	out := make([]rune, len(r))
+	for i, c := range r {
+		out[len(r)-1-i] = c
+	}
+	return string(out)
+}
+
+// l33tTable lists the letters each l33t character can stand for.
+var l33tTable = map[rune][]rune{
+	'4': {'a'}, '@': {'a'},
+	'8': {'b'},
+	'(': {'c'}, '{': {'c'}, '[': {'c'}, '<': {'c'},
+	'3': {'e'},
+	'6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'},
+	'0': {'o'},
+	'$': {'s'}, '5': {'s'},
+	'+': {'t'}, '7': {'t', 'l'},
+	'%': {'x'},
+	'2': {'z'},
+}
+
+// l33tSubs returns every way of reading the l33t characters in pw as
+// letters, one map per combination.
+func l33tSubs(pw []rune) []map[rune]rune {
+	var chars []rune
+	seen := map[rune]bool{}
+	for _, r := range pw {
+		if _, ok := l33tTable[r]; ok && !seen[r] {
+			seen[r] = true
+			chars = append(chars, r)
+		}
+	}
+	subs := []map[rune]rune{{}}
+	for _, c := range chars {
+		var next []map[rune]rune
+		for _, sub := range subs {
+			for _, letter := range l33tTable[c] {
+				s := make(map[rune]rune, len(sub)+1)
+				for k, v := range sub {
+					s[k] = v
+				}
+				s[c] = letter
+				next = append(next, s)
+			}
+		}
+		subs = next
+	}
+	if len(chars) == 0 {
+		return nil
+	}
+	return subs
+}
+
+// l33tMatches finds words spelt with l33t substitutions, like "p@ssw0rd".
+func (m matcher) l33tMatches(pw []rune) []Match {
+	lower := lowerRunes(pw)
+	var matches []Match
+	seen := map[[2]int]map[string]bool{}
+	for _, sub := range l33tSubs(lower) {
+		translated := make([]rune, len(lower))
+		for i, r := range lower {
+			if l, ok := sub[r]; ok {
+				translated[i] = l
+			} else {
+				translated[i] = r
+			}
+		}
+		for _, match := range m.dictionaryMatches(translated) {
+			token := pw[match.I : match.J+1]
+			used := map[string]string{}
+			for _, r := range lowerRunes(token) {
+				if l, ok := sub[r]; ok {
+					used[string(r)] = string(l)
+				}
+			}
+			if len(used) == 0 {
+				continue // a plain word, found by dictionaryMatches
+			}
+			if len(token) == 1 {
+				continue // a lone "4" or "@" is not a word
+			}
+			span := [2]int{match.I, match.J}
+			if seen[span] == nil {
+				seen[span] = map[string]bool{}
+			}
+			if seen[span][match.MatchedWord] {
+				continue
+			}
+			seen[span][match.MatchedWord] = true
+			match.Token = string(token)
+			match.L33t = true
+			match.Sub = used
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// ----- spatial -----
+
+// spatialMatches finds runs of three or more adjacent keys.
+func spatialMatches(pw []rune) []Match {
+	var matches []Match
+	for _, g := range graphs {
+		matches = append(matches, g.matches(pw)...)
+	}
+	return matches
+}
+
+func (g adjacencyGraph) matches(pw []rune) []Match {
+	var matches []Match
+	i := 0
+	for i < len(pw)-1 {
+		j := i + 1
+		lastDir, turns, shifted := -1, 0, 0
+		if g.name == "qwerty" && isShifted(pw[i]) {
+			shifted = 1
+		}
+		for {
+			found := false
+			if j < len(pw) {
+				cur := pw[j]
+				for dir, adj := range g.neighbors[pw[j-1]] {
+					idx := strings.IndexRune(adj, cur)
+					if adj == "" || idx < 0 {
+						continue
+					}
+					found = true
+					if idx > 0 {
+						shifted++ // the second character of a key is shifted
+					}
+					if dir != lastDir {
+						turns++
+						lastDir = dir
+					}
+					break
+				}
+			}
+			if found {
+				j++
+				continue
+			}
+			if j-i > 2 {
+				matches = append(matches, Match{
+					Pattern: "spatial",
+					I:       i,
+					J:       j - 1,
+					Token:   string(pw[i:j]),
+					Graph:   g.name,
+					Turns:   turns,
+					Shifted: shifted,
+				})
+			}
+			i = j
+			break
+		}
+	}
+	return matches
+}
+
+// isShifted reports whether r needs shift on a US keyboard.
+func isShifted(r rune) bool {
+	return unicode.IsUpper(r) || strings.ContainsRune("~!@#$%^&*()_+{}|:\"<>?", r)
+}
+
+// ----- sequence -----
+
+// maxSequenceDelta is the largest step between characters counted as a
+// sequence, e.g. "acegi" or "9630".
+const maxSequenceDelta = 5
+
+// sequenceMatches finds runs of characters with a constant step.
+func sequenceMatches(pw []rune) []Match {
+	if len(pw) < 2 {
+		return nil
+	}
+	var matches []Match
+	add := func(i, j int, delta rune) {
+		if j-i < 1 || (j-i == 1 && delta != 1 && delta != -1) {
+			return
+		}
+		if delta == 0 || delta > maxSequenceDelta || delta < -maxSequenceDelta {
+			return
+		}
+		token := pw[i : j+1]
+		name := "unicode"
+		switch t := string(token); {
+		case strings.Trim(t, "abcdefghijklmnopqrstuvwxyz") == "":
+			name = "lower"
+		case strings.Trim(t, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == "":
+			name = "upper"
+		case strings.Trim(t, "0123456789") == "":
+			name = "digits"
+		}
+		matches = append(matches, Match{
+			Pattern:      "sequence",
+			I:            i,
+			J:            j,
+			Token:        string(token),
+			SequenceName: name,
+			Ascending:    delta > 0,
+		})
+	}
+	i := 0
+	lastDelta := pw[1] - pw[0]
+	for k := 2; k < len(pw); k++ {
+		delta := pw[k] - pw[k-1]
+		if delta == lastDelta {
+			continue
+		}
+		add(i, k-1, lastDelta)
+		i = k - 1
+		lastDelta = delta
+	}
+	add(i, len(pw)-1, lastDelta)
+	return matches
+}
+
+// ----- repeat -----
+
+// repeatMatches finds a token repeated back to back, like "aaa" or
+// "abcabc". At each position the longest run wins, using the shortest base
+// that produces it.
+func (m matcher) repeatMatches(pw []rune) []Match {
+	var matches []Match
+	for i := 0; i < len(pw)-1; {
+		bestSpan, bestBase := 0, 0
+		for b := 1; i+2*b <= len(pw); b++ {
+			reps := 1
+			for i+(reps+1)*b <= len(pw) && string(pw[i+reps*b:i+(reps+1)*b]) == string(pw[i:i+b]) {
+				reps++
+			}
+			if reps >= 2 && reps*b > bestSpan {
+				bestSpan, bestBase = reps*b, b
+			}
+		}
+		if bestSpan == 0 {
+			i++
+			continue
+		}
+		base := pw[i : i+bestBase]
+		baseLg, _ := m.mostGuessable(base, m.omnimatch(base))
+		matches = append(matches, Match{
+			Pattern:     "repeat",
+			I:           i,
+			J:           i + bestSpan - 1,
+			Token:       string(pw[i : i+bestSpan]),
+			BaseToken:   string(base),
+			RepeatCount: bestSpan / bestBase,
+			lg:          baseLg,
+		})
+		i += bestSpan
+	}
+	return matches
+}
+
+// ----- dates -----
+
+// Date limits: years outside them are not read as dates.
+const (
+	minYear = 1000
+	maxYear = 2050
+)
+
+// dateSplits lists where to cut an all-digit token of each length into
+// three parts, e.g. 4 digits as 1+1+2 or 2+1+1.
+var dateSplits = map[int][][2]int{
+	4: {{1, 2}, {2, 3}},
+	5: {{1, 3}, {2, 3}},
+	6: {{1, 2}, {2, 4}, {4, 5}},
+	7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+	8: {{2, 4}, {4, 6}},
+}
+
+func isDigits(r []rune) bool {
+	for _, c := range r {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(r) > 0
+}
+
+func atoi(r []rune) int {
+	n, _ := strconv.Atoi(string(r))
+	return n
+}
+
+// dateMatches finds dates with or without separators: 13-07-1999,
+// 1999.7.13, 130799, 7131999 and so on.
+func (m matcher) dateMatches(pw []rune) []Match {
+	var matches []Match
+	for i := range pw {
+		// without separators
+		for j := i + 3; j < len(pw) && j <= i+7; j++ {
+			token := pw[i : j+1]
+			if !isDigits(token) {
+				break
+			}
+			var best *dmy
+			for _, split := range dateSplits[len(token)] {
+				d := mapIntsToDMY([3]int{atoi(token[:split[0]]), atoi(token[split[0]:split[1]]), atoi(token[split[1]:])})
+				if d == nil {
+					continue
+				}
+				if best == nil || abs(d.year-m.year) < abs(best.year-m.year) {
+					best = d
+				}
+			}
+			if best != nil {
+				matches = append(matches, dateMatch(pw, i, j, *best, ""))
+			}
+		}
+		// with separators
+		for j := i + 5; j < len(pw) && j <= i+9; j++ {
+			if d, sep, ok := separatedDate(pw[i : j+1]); ok {
+				matches = append(matches, dateMatch(pw, i, j, d, sep))
+			}
+		}
+	}
+	// drop dates inside other dates, e.g. "1999" in "13-07-1999"
+	var kept []Match
+	for a, ma := range matches {
+		inside := false
+		for b, mb := range matches {
+			if a != b && mb.I <= ma.I && mb.J >= ma.J && (mb.I != ma.I || mb.J != ma.J) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			kept = append(kept, ma)
+		}
+	}
+	return kept
+}
+
+type dmy struct{ day, month, year int }
+
+func dateMatch(pw []rune, i, j int, d dmy, sep string) Match {
+	return Match{
+		Pattern:   "date",
+		I:         i,
+		J:         j,
+		Token:     string(pw[i : j+1]),
+		Year:      d.year,
+		Month:     d.month,
+		Day:       d.day,
+		Separator: sep,
+	}
+}
+
+// separatedDate reads token as digits, a separator, 1–2 digits, the same
+// separator and more digits.
+func separatedDate(token []rune) (dmy, string, bool) {
+	first := 0
+	for first < len(token) && first < 4 && token[first] >= '0' && token[first] <= '9' {
+		first++
+	}
+	if first == 0 || first >= len(token) || !strings.ContainsRune(" /\\_.-", token[first]) {
+		return dmy{}, "", false
+	}
+	sep := token[first]
+	rest := token[first+1:]
+	second := 0
+	for second < len(rest) && second < 2 && rest[second] >= '0' && rest[second] <= '9' {
+		second++
+	}
+	if second == 0 || second >= len(rest) || rest[second] != sep {
+		return dmy{}, "", false
+	}
+	third := rest[second+1:]
+	if len(third) == 0 || len(third) > 4 || !isDigits(third) {
+		return dmy{}, "", false
+	}
+	d := mapIntsToDMY([3]int{atoi(token[:first]), atoi(rest[:second]), atoi(third)})
+	if d == nil {
+		return dmy{}, "", false
+	}
+	return *d, string(sep), true
+}
+
+// mapIntsToDMY reads three numbers as a day, month and year in any of
+// the usual orders, with the year first or last.
+func mapIntsToDMY(ints [3]int) *dmy {
+	if ints[1] > 31 || ints[1] <= 0 {
+		return nil
+	}
+	over12, over31, under1 := 0, 0, 0
+	for _, n := range ints {
+		if (n > 99 && n < minYear) || n > maxYear {
+			return nil
+		}
+		if n > 31 {
+			over31++
+		}
+		if n > 12 {
+			over12++
+		}
+		if n <= 0 {
+			under1++
+		}
+	}
+	if over31 >= 2 || over12 == 3 || under1 >= 2 {
+		return nil
+	}
+	splits := []struct {
+		year int
+		rest [2]int
+	}{
+		{ints[2], [2]int{ints[0], ints[1]}},
+		{ints[0], [2]int{ints[1], ints[2]}},
+	}
+	for _, s := range splits {
+		if s.year >= minYear && s.year <= maxYear {
+			// a four-digit year whose other parts are not a day and
+			// month is not a date
+			day, month, ok := mapIntsToDM(s.rest)
+			if !ok {
+				return nil
+			}
+			return &dmy{day, month, s.year}
+		}
+	}
+	for _, s := range splits {
+		if day, month, ok := mapIntsToDM(s.rest); ok {
+			return &dmy{day, month, twoToFourDigitYear(s.year)}
+		}
+	}
+	return nil
+}
+
+func mapIntsToDM(ints [2]int) (day, month int, ok bool) {
+	for _, dm := range [][2]int{{ints[0], ints[1]}, {ints[1], ints[0]}} {
+		if dm[0] >= 1 && dm[0] <= 31 && dm[1] >= 1 && dm[1] <= 12 {
+			return dm[0], dm[1], true
+		}
+	}
+	return 0, 0, false
+}
+
+func twoToFourDigitYear(y int) int {
+	switch {
+	case y > 99:
+		return y
+	case y > 50:
+		return y + 1900
+	default:
+		return y + 2000
+	}
+}
+
+// yearMatches finds recent four-digit years on their own, 1900–2039.
+func (m matcher) yearMatches(pw []rune) []Match {
+	var matches []Match
+	for i := 0; i+4 <= len(pw); i++ {
+		token := pw[i : i+4]
+		if !isDigits(token) {
+			continue
+		}
+		if y := atoi(token); y >= 1900 && y <= 2039 {
+			matches = append(matches, Match{Pattern: "year", I: i, J: i + 3, Token: string(token), Year: y})
+		}
+	}
+	return matches
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,303 @@
+// Package strength estimates how hard a password is to guess, in the style
+// of zxcvbn.
+//
+// Check splits a password into the sequence of patterns an attacker would
+// find cheapest to guess: dictionary words (also reversed, capitalised or
+// in l33t spelling), keyboard walks, sequences such as "abc" or "2468",
+// repeats and dates, with brute force filling the gaps. The guesses needed
+// for that sequence are turned into a 0–4 score, crack-time estimates for
+// four attack scenarios and feedback for the user.
+package strength
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ------------------------------------------------------------
+// 1. Results
+// ------------------------------------------------------------
+
+// Match is one pattern found in the password. I and J are the inclusive
+// rune offsets of Token. The fields after Guesses describe the pattern and
+// are only set for the patterns they belong to.
+type Match struct {
+	Pattern      string  `json:"pattern"`
+	I            int     `json:"i"`
+	J            int     `json:"j"`
+	Token        string  `json:"token"`
+	Guesses      float64 `json:"guesses"`
+	GuessesLog10 float64 `json:"guesses_log10"`
+
+	// dictionary
+	Dictionary  string            `json:"dictionary,omitempty"`
+	MatchedWord string            `json:"matched_word,omitempty"`
+	Rank        int               `json:"rank,omitempty"`
+	Reversed    bool              `json:"reversed,omitempty"`
+	L33t        bool              `json:"l33t,omitempty"`
+	Sub         map[string]string `json:"sub,omitempty"`
+
+	// spatial
+	Graph   string `json:"graph,omitempty"`
+	Turns   int    `json:"turns,omitempty"`
+	Shifted int    `json:"shifted_count,omitempty"`
+
+	// sequence
+	SequenceName string `json:"sequence_name,omitempty"`
+	Ascending    bool   `json:"ascending,omitempty"`
+
+	// repeat
+	BaseToken   string `json:"base_token,omitempty"`
+	RepeatCount int    `json:"repeat_count,omitempty"`
+
+	// date and year
+	Year      int    `json:"year,omitempty"`
+	Month     int    `json:"month,omitempty"`
+	Day       int    `json:"day,omitempty"`
+	Separator string `json:"separator,omitempty"`
+
+	// lg is log10 of the guesses, which is what the search works with;
+	// Guesses itself overflows for long brute-force runs.
+	lg float64
+}
+
+// CrackTimes holds the seconds needed per attack scenario: an online
+// attack throttled to 100 guesses per hour, an unthrottled online attack
+// at 10 per second, and offline attacks on a slow (1e4/s) and a fast
+// (1e10/s) hash.
+type CrackTimes struct {
+	OnlineThrottling   float64 `json:"online_throttling_100_per_hour"`
+	OnlineNoThrottling float64 `json:"online_no_throttling_10_per_second"`
+	OfflineSlowHashing float64 `json:"offline_slow_hashing_1e4_per_second"`
+	OfflineFastHashing float64 `json:"offline_fast_hashing_1e10_per_second"`
+}
+
+// CrackTimesDisplay is CrackTimes in words, e.g. "3 hours".
+type CrackTimesDisplay struct {
+	OnlineThrottling   string `json:"online_throttling_100_per_hour"`
+	OnlineNoThrottling string `json:"online_no_throttling_10_per_second"`
+	OfflineSlowHashing string `json:"offline_slow_hashing_1e4_per_second"`
+	OfflineFastHashing string `json:"offline_fast_hashing_1e10_per_second"`
+}
+
+// Feedback tells the user what makes the password weak, if anything.
+type Feedback struct {
+	Warning     string   `json:"warning"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// Result is the estimate for one password.
+type Result struct {
+	Guesses      float64 `json:"guesses"`
+	GuessesLog10 float64 `json:"guesses_log10"`
+	// Score runs from 0 (too guessable) to 4 (very unguessable).
+	Score             int               `json:"score"`
+	CrackTimesSeconds CrackTimes        `json:"crack_times_seconds"`
+	CrackTimesDisplay CrackTimesDisplay `json:"crack_times_display"`
+	Feedback          Feedback          `json:"feedback"`
+	// Sequence is the cheapest way to guess the password, in order.
+	Sequence []Match `json:"sequence"`
+}
+
+// ------------------------------------------------------------
+// 2. Estimator
+// ------------------------------------------------------------
+
+// rankedDict maps lower-case words to their rank: the number of guesses
+// an attacker working through the list needs to reach them.
+type rankedDict struct {
+	name   string
+	ranks  map[string]int
+	maxLen int
+}
+
+func newRankedDict(name string, words []string, uniform bool) rankedDict {
+	d := rankedDict{name: name, ranks: make(map[string]int, len(words))}
+	for i, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w == "" {
+			continue
+		}
+		if _, dup := d.ranks[w]; dup {
+			continue
+		}
+		d.ranks[w] = i + 1
+		if n := utf8.RuneCountInString(w); n > d.maxLen {
+			d.maxLen = n
+		}
+	}
+	if uniform {
+		// An unordered list says nothing about which words are popular,
+		// so each word costs the expected half of the list.
+		rank := max(1, len(d.ranks)/2)
+		for w := range d.ranks {
+			d.ranks[w] = rank
+		}
+	}
+	return d
+}
+
+// Estimator scores passwords against a word list. It is safe for
+// concurrent use.
+type Estimator struct {
+	dicts []rankedDict
+	// now gives the reference year that dates are measured from.
+	now func() time.Time
+}
+
+// New returns an Estimator that matches against the built-in list of
+// common passwords and against words, which is taken to be unordered: the
+// generator's dictionary, for example. Words shorter than three letters
+// are ignored.
+func New(words []string) *Estimator {
+	long := make([]string, 0, len(words))
+	for _, w := range words {
+		if utf8.RuneCountInString(w) >= 3 {
+			long = append(long, w)
+		}
+	}
+	return &Estimator{
+		dicts: []rankedDict{
+			newRankedDict("passwords", commonPasswords, false),
+			newRankedDict("words", long, true),
+		},
+		now: time.Now,
+	}
+}
+
+// Check estimates the strength of password. userInputs are strings an
+// attacker would try first, such as the user's name or e-mail address.
+func (e *Estimator) Check(password string, userInputs ...string) Result {
+	dicts := e.dicts
+	if len(userInputs) > 0 {
+		var inputs []string
+		for _, in := range userInputs {
+			inputs = append(inputs, in)
+			// also match the parts of e-mail addresses and names
+			inputs = append(inputs, strings.FieldsFunc(in, func(r rune) bool {
+				return strings.ContainsRune(" @._-+", r)
+			})...)
+		}
+		dicts = append([]rankedDict{newRankedDict("user_inputs", inputs, false)}, dicts...)
+	}
+	m := matcher{dicts: dicts, year: e.now().Year()}
+	pw := []rune(password)
+	lg, seq := m.mostGuessable(pw, m.omnimatch(pw))
+
+	res := Result{GuessesLog10: lg, Guesses: clampGuesses(lg), Sequence: seq}
+	res.Score = score(lg)
+	res.CrackTimesSeconds, res.CrackTimesDisplay = crackTimes(lg)
+	res.Feedback = feedback(res.Score, seq)
+	for i := range res.Sequence {
+		res.Sequence[i].Guesses = clampGuesses(res.Sequence[i].lg)
+		res.Sequence[i].GuessesLog10 = res.Sequence[i].lg
+	}
+	return res
+}
+
+// ------------------------------------------------------------
+// 3. Score and crack times
+// ------------------------------------------------------------
+
+// Capped returns r with its guesses lowered to at most 10^lg and the score
+// and crack times recomputed to match, for callers that know the password
+// is easier to guess than its patterns suggest. The sequence and feedback
+// are left as they are.
+func (r Result) Capped(lg float64) Result {
+	if r.GuessesLog10 <= lg {
+		return r
+	}
+	r.GuessesLog10, r.Guesses = lg, clampGuesses(lg)
+	r.Score = score(lg)
+	r.CrackTimesSeconds, r.CrackTimesDisplay = crackTimes(lg)
+	return r
+}
+
+// clampGuesses converts log10 guesses back, capped to stay encodable and
+// rounded to a whole number while that is exact.
+func clampGuesses(lg float64) float64 {
+	g := math.Pow(10, math.Min(lg, 300))
+	if g < 1<<53 {
+		g = math.Round(g)
+	}
+	return g
+}
+
+// score buckets guesses like zxcvbn: below 10^3 is 0, 10^6 is 1, 10^8 is
+// 2, 10^10 is 3, anything more is 4. The small delta keeps passwords that
+// sit exactly on a boundary in the lower bucket.
+func score(lg float64) int {
+	const delta = 5
+	g := math.Pow(10, lg)
+	switch {
+	case g < 1e3+delta:
+		return 0
+	case g < 1e6+delta:
+		return 1
+	case g < 1e8+delta:
+		return 2
+	case g < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func crackTimes(lg float64) (CrackTimes, CrackTimesDisplay) {
+	g := clampGuesses(lg)
+	s := CrackTimes{
+		OnlineThrottling:   g / (100.0 / 3600),
+		OnlineNoThrottling: g / 10,
+		OfflineSlowHashing: g / 1e4,
+		OfflineFastHashing: g / 1e10,
+	}
+	return s, CrackTimesDisplay{
+		OnlineThrottling:   displayTime(s.OnlineThrottling),
+		OnlineNoThrottling: displayTime(s.OnlineNoThrottling),
+		OfflineSlowHashing: displayTime(s.OfflineSlowHashing),
+		OfflineFastHashing: displayTime(s.OfflineFastHashing),
+	}
+}
+
+// displayTime renders seconds as "3 hours", "less than a second" or
+// "centuries".
+func displayTime(seconds float64) string {
+	const (
+		minute  = 60
+		hour    = 60 * minute
+		day     = 24 * hour
+		month   = 31 * day
+		year    = 12 * month
+		century = 100 * year
+	)
+	units := []struct {
+		size float64
+		name string
+	}{
+		{year, "year"},
+		{month, "month"},
+		{day, "day"},
+		{hour, "hour"},
+		{minute, "minute"},
+		{1, "second"},
+	}
+	switch {
+	case seconds < 1:
+		return "less than a second"
+	case seconds >= century:
+		return "centuries"
+	}
+	for _, u := range units {
+		if seconds >= u.size {
+			n := int(math.Round(seconds / u.size))
+			if n == 1 {
+				return "1 " + u.name
+			}
+			return strconv.Itoa(n) + " " + u.name + "s"
+		}
+	}
+	return "less than a second"
+}
@@ -0,0 +1,180 @@
+package strength
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEstimator dates passwords against a fixed year so the date cases do
+// not drift.
+func testEstimator(words ...string) *Estimator {
+	e := New(words)
+	e.now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return e
+}
+
+func TestScore(t *testing.T) {
+	cases := []struct {
+		lg   float64
+		want int
+	}{
+		{0, 0},
+		{3, 0}, // exactly on a boundary stays in the lower bucket
+		{3.01, 1},
+		{6, 1},
+		{6.01, 2},
+		{8, 2},
+		{8.01, 3},
+		{10, 3},
+		{10.01, 4},
+		{40, 4},
+	}
+	for _, c := range cases {
+		if got := score(c.lg); got != c.want {
+			t.Errorf("score(%v) = %d, want %d", c.lg, got, c.want)
+		}
+	}
+}
+
+func TestCheckWeakPasswords(t *testing.T) {
+	cases := []struct {
+		pwd string
+		// pattern is what the whole password should be matched as
+		pattern  string
+		maxScore int
+	}{
+		{"password", "dictionary", 0},
+		{"123456", "dictionary", 0},
+		{"qwerty", "dictionary", 0},
+		{"drowssap", "dictionary", 1}, // reversed
+		{"p@ssw0rd", "dictionary", 1}, // l33t
+		{"PASSWORD", "dictionary", 1},
+		{"zzzzzzzzzz", "repeat", 1},
+		{"abcdefghij", "sequence", 1},
+		{"9876543210", "sequence", 1},
+		{"sdfghjkl", "spatial", 1},
+		{"13/04/1998", "date", 1},
+		{"1987", "year", 0},
+	}
+	e := testEstimator()
+	for _, c := range cases {
+		res := e.Check(c.pwd)
+		if res.Score > c.maxScore {
+			t.Errorf("%q: score %d, want at most %d", c.pwd, res.Score, c.maxScore)
+		}
+		if len(res.Sequence) != 1 || res.Sequence[0].Pattern != c.pattern {
+			t.Errorf("%q: matched as %+v, want one %s match", c.pwd, res.Sequence, c.pattern)
+		}
+		if res.Feedback.Warning == "" && len(res.Feedback.Suggestions) == 0 {
+			t.Errorf("%q: no feedback", c.pwd)
+		}
+	}
+}
+
+func TestCheckStrongPasswords(t *testing.T) {
+	e := testEstimator()
+	for _, pwd := range []string{
+		"Xq7#pL2!vR9@kT4$",
+		"correct horse battery staple",
+		"vD3p-J8wq-N2zk-F6ym",
+	} {
+		res := e.Check(pwd)
+		if res.Score != 4 {
+			t.Errorf("%q: score %d, want 4", pwd, res.Score)
+		}
+		if res.Feedback.Warning != "" || len(res.Feedback.Suggestions) != 0 {
+			t.Errorf("%q: unexpected feedback %+v", pwd, res.Feedback)
+		}
+	}
+}
+
+func TestCheckSequenceCoversPassword(t *testing.T) {
+	e := testEstimator("staple")
+	for _, pwd := range []string{"", "a", "password1", "Tr0ub4dour&3", "staple2026!", "xÄöü€漢字"} {
+		res := e.Check(pwd)
+		var b strings.Builder
+		next := 0
+		for _, m := range res.Sequence {
+			if m.I != next || m.J < m.I {
+				t.Errorf("%q: match %q at [%d,%d], want it to start at %d", pwd, m.Token, m.I, m.J, next)
+			}
+			b.WriteString(m.Token)
+			next = m.J + 1
+		}
+		if b.String() != pwd {
+			t.Errorf("%q: sequence spells %q", pwd, b.String())
+		}
+		if math.IsNaN(res.GuessesLog10) || res.Guesses < 1 {
+			t.Errorf("%q: guesses %v (log10 %v)", pwd, res.Guesses, res.GuessesLog10)
+		}
+	}
+}
+
+func TestCheckUserInputs(t *testing.T) {
+	e := testEstimator()
+	pwd := "quixotic.marmalade"
+	without := e.Check(pwd)
+	with := e.Check(pwd, "Quixotic Marmalade", "q.marmalade@example.com")
+	if with.GuessesLog10 >= without.GuessesLog10 {
+		t.Errorf("user inputs did not help: %v guesses with, %v without", with.GuessesLog10, without.GuessesLog10)
+	}
+	if with.Sequence[0].Dictionary != "user_inputs" {
+		t.Errorf("first match %+v, want a user_inputs match", with.Sequence[0])
+	}
+}
+
+func TestCheckWords(t *testing.T) {
+	pwd := "velocipedezeppelin"
+	without := testEstimator().Check(pwd)
+	with := testEstimator("velocipede", "zeppelin", "of").Check(pwd)
+	if with.Score >= without.Score {
+		t.Errorf("dictionary words did not lower the score: %d with, %d without", with.Score, without.Score)
+	}
+	for _, m := range with.Sequence {
+		if m.Dictionary != "words" {
+			t.Errorf("%q matched as %s/%s, want the words dictionary", m.Token, m.Pattern, m.Dictionary)
+		}
+	}
+}
+
+func TestCapped(t *testing.T) {
+	e := testEstimator()
+	strong := e.Check("Xq7#pL2!vR9@kT4$")
+	got := strong.Capped(3)
+	if got.Score != 0 || got.Guesses != 1000 || got.GuessesLog10 != 3 {
+		t.Errorf("capped strong password: score %d, guesses %v (log10 %v)", got.Score, got.Guesses, got.GuessesLog10)
+	}
+	if got.CrackTimesDisplay.OfflineSlowHashing != "less than a second" || got.CrackTimesDisplay.OnlineThrottling != "10 hours" {
+		t.Errorf("capped strong password: crack times %+v", got.CrackTimesDisplay)
+	}
+	if len(got.Sequence) != len(strong.Sequence) {
+		t.Errorf("capping changed the sequence")
+	}
+	// a password already under the cap is left alone
+	weak := e.Check("password")
+	if got := weak.Capped(3); got.GuessesLog10 != weak.GuessesLog10 || got.CrackTimesSeconds != weak.CrackTimesSeconds {
+		t.Errorf("capping a weak password changed it: %+v", got)
+	}
+}
+
+func TestDisplayTime(t *testing.T) {
+	cases := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "less than a second"},
+		{1, "1 second"},
+		{59, "59 seconds"},
+		{60, "1 minute"},
+		{3 * 3600, "3 hours"},
+		{2 * 86400, "2 days"},
+		{100 * 12 * 31 * 86400, "centuries"},
+	}
+	for _, c := range cases {
+		if got := displayTime(c.seconds); got != c.want {
+			t.Errorf("displayTime(%v) = %q, want %q", c.seconds, got, c.want)
+		}
+	}
+}
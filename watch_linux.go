@@ -0,0 +1,54 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log"
+	"os"
+	"syscall"
+)
+
+// watchDir signals events whenever inotify reports a matching file in dir
+// being written, created, renamed or removed.
+func watchDir(dir string, match func(string) bool, events chan<- struct{}) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return os.NewSyscallError("inotify_init1", err)
+	}
+	const mask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+		syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		syscall.Close(fd)
+		return os.NewSyscallError("inotify_add_watch", err)
+	}
+	go func() {
+		defer syscall.Close(fd)
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := syscall.Read(fd, buf)
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			if err != nil || n <= 0 {
+				log.Printf("Stopped watching %s: %v", dir, os.NewSyscallError("read", err))
+				return
+			}
+			// Each event is a struct inotify_event followed by Len bytes
+			// of NUL-padded file name.
+			for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+				var ev syscall.InotifyEvent
+				binary.Read(bytes.NewReader(buf[off:off+syscall.SizeofInotifyEvent]), binary.NativeEndian, &ev)
+				off += syscall.SizeofInotifyEvent
+				name := string(bytes.TrimRight(buf[off:off+int(ev.Len)], "\x00"))
+				off += int(ev.Len)
+				if ev.Mask&syscall.IN_Q_OVERFLOW != 0 || match(name) {
+					notify(events)
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// watchDir needs inotify; elsewhere the caller falls back to polling.
+func watchDir(dir string, match func(string) bool, events chan<- struct{}) error {
+	return errors.ErrUnsupported
+}